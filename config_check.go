@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Henry-Case-dev/rofloslav/internal/config"
+	"github.com/Henry-Case-dev/rofloslav/internal/gemini"
+	"github.com/Henry-Case-dev/rofloslav/internal/storage"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// runConfigCheck выполняет проверку конфигурации без запуска бота (CONFIG_CHECK=true):
+// токен Telegram, модель генерации и эмбеддингов Gemini, подключение к Qdrant и директорию данных.
+// Проверка ничего не меняет: коллекция Qdrant и DATA_DIR не создаются.
+// Печатает сводку и возвращает код завершения (0 - все проверки пройдены).
+func runConfigCheck(ctx context.Context, cfg *config.Config) int {
+	log.Println("=== CONFIG_CHECK: проверка конфигурации без запуска бота ===")
+	var results []string
+	failed := 0
+	check := func(name string, err error, details string) {
+		if err != nil {
+			failed++
+			results = append(results, fmt.Sprintf("[FAIL] %s: %v", name, err))
+			return
+		}
+		results = append(results, fmt.Sprintf("[ OK ] %s: %s", name, details))
+	}
+
+	// Telegram: getMe по токену
+	tgAPI, err := tgbotapi.NewBotAPI(cfg.TelegramToken)
+	botName := ""
+	if err == nil {
+		botName = "@" + tgAPI.Self.UserName
+	}
+	check("Telegram", err, botName)
+
+	// Gemini: тестовая генерация и тестовый эмбеддинг
+	timeout := time.Duration(cfg.ResponseTimeoutSec) * time.Second
+	geminiClient, err := gemini.NewClient(ctx, cfg.GeminiAPIKey, cfg.GeminiModelName, cfg.GeminiEmbeddingModelName, timeout, cfg.GeminiSafetyBlockRetry)
+	if err != nil {
+		check("Gemini", err, "")
+	} else {
+		defer geminiClient.Close()
+
+		genCtx, cancel := context.WithTimeout(ctx, timeout)
+		_, genErr := geminiClient.GenerateArbitraryContent(genCtx, "Ответь одним словом: ок", cfg.DefaultArbitraryGenerationSettings)
+		cancel()
+		check("Gemini генерация", genErr, cfg.GeminiModelName)
+
+		embCtx, cancel := context.WithTimeout(ctx, timeout)
+		embedding, embErr := geminiClient.GetEmbedding(embCtx, "test")
+		cancel()
+		check("Gemini эмбеддинги", embErr, fmt.Sprintf("%s, размерность %d", cfg.GeminiEmbeddingModelName, len(embedding)))
+	}
+
+	// Qdrant: подключение и состояние коллекции, только запросы на чтение
+	stats, qErr := storage.ProbeQdrant(cfg)
+	details := fmt.Sprintf("коллекция %s не найдена, будет создана при запуске", cfg.QdrantCollection)
+	if stats != nil {
+		details = fmt.Sprintf("коллекция %s, статус %s, точек %d", stats.Name, stats.Status, stats.PointsCount)
+	}
+	check("Qdrant", qErr, details)
+
+	// Локальное хранилище: права на запись в директорию данных
+	dataDir, dirErr := storage.CheckDataDir()
+	check("Локальное хранилище", dirErr, dataDir)
+
+	log.Println("=== CONFIG_CHECK: результаты ===")
+	for _, line := range results {
+		log.Println(line)
+	}
+	if failed > 0 {
+		log.Printf("=== CONFIG_CHECK: провалено проверок: %d ===", failed)
+		return 1
+	}
+	log.Println("=== CONFIG_CHECK: все проверки пройдены ===")
+	return 0
+}
@@ -0,0 +1,197 @@
+package bot
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/Henry-Case-dev/rofloslav/internal/storage"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// isAdmin проверяет, входит ли пользователь в список администраторов бота (ADMIN_USER_IDS).
+func (b *Bot) isAdmin(userID int64) bool {
+	for _, adminID := range b.config.AdminUserIDs {
+		if adminID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// isIgnoredSender проверяет, нужно ли игнорировать автора сообщения: пользователи из IGNORED_USER_IDS
+// и другие боты (при IGNORE_OTHER_BOTS). Такие сообщения не сохраняются и не получают ответа,
+// что защищает от зацикливания ботов друг на друге.
+func (b *Bot) isIgnoredSender(from *tgbotapi.User) bool {
+	if from == nil {
+		return false
+	}
+	if b.config.IgnoreOtherBots && from.IsBot {
+		return true
+	}
+	for _, ignoredID := range b.config.IgnoredUserIDs {
+		if ignoredID == from.ID {
+			return true
+		}
+	}
+	return false
+}
+
+// handleDeleteCommand удаляет из всех хранилищ сообщение, на которое ответили командой /delete.
+// Удалить сообщение может его автор или администратор бота.
+func (b *Bot) handleDeleteCommand(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	target := message.ReplyToMessage
+	if target == nil {
+		b.sendReply(chatID, "Ответьте командой /delete на сообщение, которое нужно удалить из памяти бота.")
+		return
+	}
+	isAuthor := message.From != nil && target.From != nil && target.From.ID == message.From.ID
+	if message.From == nil || !(isAuthor || b.isAdmin(message.From.ID)) {
+		b.sendReply(chatID, "Удалить сообщение из памяти может только его автор или администратор бота.")
+		return
+	}
+
+	log.Printf("[Admin] Чат %d: Пользователь %d удаляет сообщение %d из хранилищ.", chatID, message.From.ID, target.MessageID)
+	err := b.storage.DeleteMessage(chatID, target.MessageID)
+	if b.localHistory != b.storage {
+		if localErr := b.localHistory.DeleteMessage(chatID, target.MessageID); localErr != nil {
+			log.Printf("[Admin WARN] Чат %d: Ошибка удаления сообщения %d из локального хранилища: %v", chatID, target.MessageID, localErr)
+		}
+	}
+	if err != nil {
+		log.Printf("[Admin ERROR] Чат %d: Ошибка удаления сообщения %d: %v", chatID, target.MessageID, err)
+		b.sendReply(chatID, "Не удалось удалить сообщение из памяти. Попробуйте позже.")
+		return
+	}
+	b.sendReplyToUser(chatID, message.MessageID, "Сообщение удалено из памяти бота.")
+}
+
+// handleRawCommand показывает сохраненный полный JSON сообщения, на которое ответили командой /raw
+// (STORE_RAW_UPDATES). Ищет сначала в основном хранилище, затем в локальном.
+func (b *Bot) handleRawCommand(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	target := message.ReplyToMessage
+	if target == nil {
+		b.sendReply(chatID, "Ответьте командой /raw на сообщение, JSON которого нужно показать.")
+		return
+	}
+
+	stores := []storage.HistoryStorage{b.storage}
+	if b.localHistory != b.storage {
+		stores = append(stores, b.localHistory)
+	}
+	var raw []byte
+	supported := false
+	for _, store := range stores {
+		data, err := store.GetRawMessage(chatID, target.MessageID)
+		if errors.Is(err, storage.ErrNotSupported) {
+			continue
+		}
+		supported = true
+		if err != nil {
+			log.Printf("[Admin WARN] Чат %d: Ошибка получения JSON сообщения %d из %s: %v", chatID, target.MessageID, store.BackendName(), err)
+			continue
+		}
+		if data != nil {
+			raw = data
+			break
+		}
+	}
+	if !supported {
+		b.sendReply(chatID, "Полный JSON сообщений не сохраняется (STORE_RAW_UPDATES=false).")
+		return
+	}
+	if raw == nil {
+		b.sendReply(chatID, "JSON этого сообщения не найден в хранилищах.")
+		return
+	}
+
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, raw, "", "  "); err == nil {
+		raw = indented.Bytes()
+	}
+	// Без Markdown: в JSON полно символов разметки
+	for _, chunk := range splitMessage(string(raw), splitChunkLimit) {
+		if _, err := b.send(chatID, tgbotapi.NewMessage(chatID, chunk)); err != nil {
+			log.Printf("[Admin ERROR] Чат %d: Не удалось отправить JSON сообщения %d: %v", chatID, target.MessageID, err)
+			return
+		}
+	}
+}
+
+// handleForgetUserCommand удаляет всю историю пользователя в чате из всех хранилищ.
+// Пользователь указывается ответом на его сообщение или как /forget_user @username.
+func (b *Bot) handleForgetUserCommand(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+
+	var userID int64
+	var userLabel string
+	if target := message.ReplyToMessage; target != nil && target.From != nil {
+		userID = target.From.ID
+		userLabel = displayName(target.From.UserName, target.From.FirstName)
+	} else if arg := strings.TrimSpace(message.CommandArguments()); arg != "" {
+		userLabel = arg
+		userID = b.findUserIDByUsername(chatID, strings.TrimPrefix(arg, "@"))
+		if userID == 0 {
+			b.sendReply(chatID, fmt.Sprintf("Не нашел %s в недавней истории чата. Ответьте командой на сообщение пользователя.", arg))
+			return
+		}
+	} else {
+		b.sendReply(chatID, "Ответьте командой /forget_user на сообщение пользователя или укажите его: /forget_user @username")
+		return
+	}
+
+	log.Printf("[Admin] Чат %d: Администратор %d удаляет историю пользователя %d.", chatID, message.From.ID, userID)
+	err := b.storage.ClearUserHistory(chatID, userID)
+	if b.localHistory != b.storage {
+		if localErr := b.localHistory.ClearUserHistory(chatID, userID); localErr != nil {
+			log.Printf("[Admin WARN] Чат %d: Ошибка удаления истории пользователя %d из локального хранилища: %v", chatID, userID, localErr)
+		}
+	}
+	if err != nil {
+		log.Printf("[Admin ERROR] Чат %d: Ошибка удаления истории пользователя %d: %v", chatID, userID, err)
+		b.sendReply(chatID, "Не удалось удалить историю пользователя. Попробуйте позже.")
+		return
+	}
+	b.sendReply(chatID, fmt.Sprintf("История пользователя %s удалена из памяти бота.", userLabel))
+}
+
+// findUserIDByUsername ищет ID пользователя по username в локальной истории чата (0 - не найден).
+func (b *Bot) findUserIDByUsername(chatID int64, username string) int64 {
+	for _, msg := range b.localHistory.GetMessages(chatID) {
+		if msg.From != nil && strings.EqualFold(msg.From.UserName, username) {
+			return msg.From.ID
+		}
+	}
+	return 0
+}
+
+// handleQdrantStatsCommand показывает администратору размер и состояние коллекции Qdrant.
+func (b *Bot) handleQdrantStatsCommand(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+
+	if !b.storage.SupportsVectorSearch() {
+		b.sendReply(chatID, fmt.Sprintf("Основное хранилище (%s) без векторной коллекции, статистика недоступна.", b.storage.BackendName()))
+		return
+	}
+
+	stats, err := b.storage.GetCollectionInfo()
+	if err != nil {
+		log.Printf("[Admin ERROR] Чат %d: Ошибка получения статистики Qdrant: %v", chatID, err)
+		b.sendReply(chatID, "Не удалось получить статистику коллекции Qdrant.")
+		return
+	}
+
+	optimizer := "ok"
+	if !stats.OptimizerOK {
+		optimizer = "ошибка: " + stats.OptimizerError
+	}
+	b.sendReply(chatID, fmt.Sprintf(
+		"Коллекция Qdrant: %s\nСтатус: %s\nОптимизатор: %s\nТочек: %d\nВекторов: %d\nПроиндексировано векторов: %d\nСегментов: %d",
+		stats.Name, stats.Status, optimizer, stats.PointsCount, stats.VectorsCount, stats.IndexedVectorsCount, stats.SegmentsCount,
+	))
+}
@@ -0,0 +1,54 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// llmFailureAlertThreshold - после скольких ошибок генерации подряд оповещать администратора.
+const llmFailureAlertThreshold = 3
+
+// notifyAdmin отправляет оповещение в ADMIN_NOTIFY_CHAT_ID. Оповещения с одним ключом (kind)
+// отправляются не чаще раза в ADMIN_NOTIFY_COOLDOWN, чтобы повторяющаяся ошибка не заспамила чат.
+func (b *Bot) notifyAdmin(kind string, format string, args ...interface{}) {
+	if b.config.AdminNotifyChatID == 0 {
+		return
+	}
+	b.adminNotifyMutex.Lock()
+	now := time.Now()
+	if last, ok := b.adminNotifyTimes[kind]; ok && now.Sub(last) < b.config.AdminNotifyCooldown {
+		b.adminNotifyMutex.Unlock()
+		return
+	}
+	b.adminNotifyTimes[kind] = now
+	b.adminNotifyMutex.Unlock()
+
+	text := "⚠️ " + fmt.Sprintf(format, args...)
+	log.Printf("[Admin Notify] %s", text)
+	// Отправляем в фоне: ожидание лимитов Telegram не должно задерживать обработку сообщений
+	go b.sendReply(b.config.AdminNotifyChatID, text)
+}
+
+// recordLLMResult считает ошибки генерации подряд и оповещает администратора, когда их становится
+// llmFailureAlertThreshold. Успешный ответ сбрасывает счетчик.
+func (b *Bot) recordLLMResult(chatID int64, err error) {
+	// Отсутствие LLM и исчерпанный лимит - не сбои модели, о лимите оповещает reserveLLMCall
+	if errors.Is(err, errLLMUnavailable) || errors.Is(err, errLLMBudgetExceeded) {
+		return
+	}
+	b.adminNotifyMutex.Lock()
+	if err == nil {
+		b.llmFailures = 0
+		b.adminNotifyMutex.Unlock()
+		return
+	}
+	b.llmFailures++
+	failures := b.llmFailures
+	b.adminNotifyMutex.Unlock()
+
+	if failures >= llmFailureAlertThreshold {
+		b.notifyAdmin("llm", "LLM не отвечает: %d ошибок генерации подряд. Последняя (чат %d): %v", failures, chatID, err)
+	}
+}
@@ -2,12 +2,13 @@ package bot
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"math/rand"
-	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/Henry-Case-dev/rofloslav/internal/config"
@@ -26,20 +27,68 @@ type Bot struct {
 	localHistory       storage.HistoryStorage // Дополнительное локальное хранилище для саммари/контекста
 	config             *config.Config
 	stop               chan struct{}
+	stopOnce           sync.Once
+	shutdownWG         sync.WaitGroup // Горутины, которые сохраняют состояние при остановке (Stop ждет их)
 	chatSettings       map[int64]*ChatSettings
 	settingsMutex      sync.RWMutex
-	lastSummaryRequest map[int64]time.Time
+	settingsSaveTimers map[int64]*time.Timer // Отложенные сохранения настроек чатов (scheduleChatSettingsSave)
+	settingsSaveMutex  sync.Mutex
+	lastSummaryRequest map[int64]*summaryState // Кулдаун и кеш последнего саммари по чатам
 	summaryMutex       sync.Mutex
 	// Добавляем поле для хранения времени последнего прямого ответа для каждого пользователя в каждом чате
 	directReplyTimestamps map[int64]map[int64][]time.Time // map[chatID][userID][]timestamps
 	directReplyMutex      sync.Mutex
 	botID                 int64
-	responseTimeout       time.Duration // Таймаут для ответов Gemini
+	loadedChats           map[int64]bool // Чаты, история которых уже загружена (LAZY_HISTORY_LOAD)
+	loadedChatsMutex      sync.Mutex
+	fallbackReplyTimes    map[int64]time.Time // Время последнего FALLBACK_REPLY_TEXT по чатам
+	fallbackReplyMutex    sync.Mutex
+	welcomeTimes          map[int64]time.Time // Время последнего приветствия новых участников по чатам
+	welcomeMutex          sync.Mutex
+	joinTimes             map[int64][]time.Time // Время недавних вступлений по чатам (JOIN_FLOOD_THRESHOLD)
+	joinMutex             sync.Mutex
+	mediaTimes            map[int64][]time.Time // Время недавних медиа без подписи по чатам (темп чата для ADAPTIVE_TRIGGER)
+	mediaMutex            sync.Mutex
+	pendingCaptchas       map[int64]map[int64]*pendingCaptcha // Ожидающие проверки вступивших: map[chatID][userID]
+	captchaMutex          sync.Mutex
+	reminders             map[int64]*reminder // Ожидающие напоминания (/remind) по номерам
+	lastReminderID        int64               // Последний выданный номер напоминания
+	remindersMutex        sync.Mutex
+	membershipCache       map[[2]int64]chatMembership // Участие пользователей в чатах для inline-поиска: [chatID, userID]
+	membershipMutex       sync.Mutex
+	chatAdminsCache       map[int64]chatAdmins // Администраторы Telegram-чатов для команд с chatAdminAllowed
+	chatAdminsMutex       sync.Mutex
+	sendLimiter           *sendLimiter         // Ограничение частоты исходящих сообщений (лимиты Telegram)
+	adminNotifyTimes      map[string]time.Time // Время последнего оповещения ADMIN_NOTIFY_CHAT_ID по типам
+	llmFailures           int                  // Ошибок генерации подряд (для оповещения администратора)
+	adminNotifyMutex      sync.Mutex
+	repliesMuted          atomic.Bool    // Глобальная пауза ответов (/mute_bot), сохраняется в DATA_DIR/bot_state.json
+	llmBudget             *llmBudget     // Счетчик вызовов LLM за сутки (LLM_DAILY_CALL_BUDGET)
+	responseCache         *responseCache // Кеш ответов на произвольные промпты (LLM_RESPONSE_CACHE), nil - отключен
+	responseTimeout       time.Duration  // Таймаут для ответов Gemini
 }
 
 // ChatSettings содержит специфичные для чата настройки.
 type ChatSettings struct {
-	Active bool
+	Active     bool
+	UseReplyTo bool // Отвечать реплаем на сообщение (true) или отдельным сообщением (false)
+	// Включать собственные сообщения бота в контекст LLM. Отключение помогает,
+	// если бот начинает повторять сам себя или зацикливается на своей персоне.
+	IncludeOwnMessages bool
+	QuoteOfDayEnabled  bool   // Публиковать "цитату дня" (если включено глобально QUOTE_OF_DAY_ENABLED)
+	Language           string // Код языка промптов по умолчанию (/setlang); пусто - ru
+	StatsDigestEnabled bool   // Публиковать еженедельную статистику (если включено глобально STATS_DIGEST_ENABLED)
+	Model              string // Модель генерации чата (/setmodel); пусто - GEMINI_MODEL_NAME
+	RepliesEnabled     bool   // Отвечать в чате (/bot_talk, /bot_quiet); сообщения сохраняются в любом случае
+	// Команды, отключенные в чате (/disable_cmd), без "/"
+	DisabledCommands []string
+	MoodsEnabled     bool // Добавлять к промпту случайное настроение из PERSONA_MOODS
+	// Удалять сообщения со словами из WORD_FILTER (боту нужны права на удаление сообщений)
+	WordFilterEnabled bool
+	WelcomeEnabled    bool // Приветствовать новых участников (WELCOME_MESSAGE / WELCOME_PROMPT)
+	// Проверять вступивших кнопкой (JOIN_CAPTCHA_TIMEOUT), не прошедших - исключать
+	JoinCaptchaEnabled bool
+	Rules              string // Правила чата (/setrules); пусто - DEFAULT_RULES
 	// Добавить другие настройки по мере необходимости (например, язык, персона)
 }
 
@@ -53,6 +102,9 @@ func NewBot(cfg *config.Config, geminiClient *gemini.Client, primaryStorage stor
 	tgAPI.Debug = cfg.Debug
 	log.Printf("Авторизован как %s", tgAPI.Self.UserName)
 
+	configuredStorage := primaryStorage
+	primaryStorage = checkLongTermMemory(cfg, geminiClient, primaryStorage, localHistoryStorage)
+
 	b := &Bot{
 		api:                   tgAPI,
 		gemini:                geminiClient,
@@ -62,24 +114,92 @@ func NewBot(cfg *config.Config, geminiClient *gemini.Client, primaryStorage stor
 		stop:                  make(chan struct{}),
 		chatSettings:          make(map[int64]*ChatSettings),
 		settingsMutex:         sync.RWMutex{},
-		lastSummaryRequest:    make(map[int64]time.Time),
+		settingsSaveTimers:    make(map[int64]*time.Timer),
+		lastSummaryRequest:    make(map[int64]*summaryState),
 		summaryMutex:          sync.Mutex{},
 		directReplyTimestamps: make(map[int64]map[int64][]time.Time),
 		directReplyMutex:      sync.Mutex{},
+		loadedChats:           make(map[int64]bool),
+		fallbackReplyTimes:    make(map[int64]time.Time),
+		welcomeTimes:          make(map[int64]time.Time),
+		joinTimes:             make(map[int64][]time.Time),
+		mediaTimes:            make(map[int64][]time.Time),
+		pendingCaptchas:       make(map[int64]map[int64]*pendingCaptcha),
+		reminders:             make(map[int64]*reminder),
+		membershipCache:       make(map[[2]int64]chatMembership),
+		chatAdminsCache:       make(map[int64]chatAdmins),
+		sendLimiter:           newSendLimiter(cfg.TelegramSendRate, cfg.TelegramChatSendInterval),
+		adminNotifyTimes:      make(map[string]time.Time),
+		llmBudget:             newLLMBudget(cfg.TimeZone),
 		botID:                 tgAPI.Self.ID,
 		responseTimeout:       time.Duration(cfg.ResponseTimeoutSec) * time.Second,
 	}
 
-	// Загрузка существующих настроек чатов (если есть)
-	// b.loadChatSettings() // TODO: Implement loading if needed
+	if primaryStorage != configuredStorage {
+		b.notifyAdmin("long_term_memory", "Долговременная память отключена: модель эмбеддингов %s недоступна, хранилище %s не используется до перезапуска.",
+			cfg.GeminiEmbeddingModelName, configuredStorage.BackendName())
+	}
+
+	if cfg.LLMResponseCache {
+		b.responseCache = newResponseCache(cfg.LLMResponseCacheTTL)
+	}
+
+	// Загрузка сохраненных настроек чатов и паузы ответов
+	b.loadBotState()
+	b.loadChatSettings()
 
 	// Запуск планировщиков
-	// go b.autoSummarizeScheduler()
 	// go b.cleanupScheduler()
+	// Лимиты прямых обращений переживают перезапуск
+	b.loadDirectReplyState()
+	b.shutdownWG.Add(2)
+	go b.directReplyStateSaver()
+	go b.chatSettingsSaver()
+	b.loadReminders()
+	if cfg.SummaryIntervalHours > 0 {
+		go b.autoSummarizeScheduler()
+	}
+	if cfg.QuoteOfDayEnabled {
+		go b.quoteOfDayScheduler()
+	}
+	if cfg.StatsDigestEnabled {
+		go b.statsDigestScheduler()
+	}
 
 	return b, nil
 }
 
+// checkLongTermMemory делает один тестовый эмбеддинг, если основное хранилище использует векторный поиск (долговременная память).
+// Если модель эмбеддингов недоступна, память отключается: вместо него используется локальное хранилище,
+// чтобы каждое сообщение не заканчивалось ошибкой эмбеддинга в логах.
+func checkLongTermMemory(cfg *config.Config, geminiClient *gemini.Client, primaryStorage, localHistoryStorage storage.HistoryStorage) storage.HistoryStorage {
+	if !primaryStorage.SupportsVectorSearch() {
+		return primaryStorage
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.ResponseTimeoutSec)*time.Second)
+	defer cancel()
+	embedding, err := geminiClient.GetEmbedding(ctx, "test")
+	if err == nil && len(embedding) > 0 {
+		return primaryStorage
+	}
+	if err == nil {
+		err = fmt.Errorf("получен пустой эмбеддинг")
+	}
+
+	log.Printf("!!! WARNING: Модель эмбеддингов %s недоступна: %v", cfg.GeminiEmbeddingModelName, err)
+	log.Printf("!!! WARNING: ДОЛГОВРЕМЕННАЯ ПАМЯТЬ ОТКЛЮЧЕНА. Сообщения не будут сохраняться в %s до перезапуска с рабочей моделью эмбеддингов.", primaryStorage.BackendName())
+	if localHistoryStorage != nil {
+		return localHistoryStorage
+	}
+	localStorage, localErr := storage.NewLocalStorage(cfg)
+	if localErr != nil {
+		log.Printf("[Bot ERROR] Не удалось создать локальное хранилище вместо Qdrant, продолжаем с Qdrant: %v", localErr)
+		return primaryStorage
+	}
+	return localStorage
+}
+
 // Run запускает основного цикла обработки сообщений бота.
 func (b *Bot) Run() {
 	log.Println("Запуск бота...")
@@ -99,9 +219,11 @@ func (b *Bot) Run() {
 	}
 }
 
-// Stop останавливает работу бота.
+// Stop останавливает работу бота и ждет, пока будут сохранены отложенные настройки чатов
+// и состояние лимитов. Повторные вызовы безопасны.
 func (b *Bot) Stop() {
-	close(b.stop)
+	b.stopOnce.Do(func() { close(b.stop) })
+	b.shutdownWG.Wait()
 }
 
 // handleUpdate обрабатывает входящие обновления от Telegram.
@@ -111,17 +233,54 @@ func (b *Bot) handleUpdate(update tgbotapi.Update) {
 	if update.Message != nil {
 		message = update.Message
 	} else if update.EditedMessage != nil {
-		message = update.EditedMessage
-		log.Printf("Получено отредактированное сообщение %d в чате %d", message.MessageID, message.Chat.ID)
-		// TODO: Решить, как обрабатывать отредактированные сообщения (обновлять в хранилище?)
-		// Пока просто логируем и обрабатываем как новое для простоты
+		// Отредактированное сообщение только обновляем в хранилищах, повторно не отвечаем
+		b.handleEditedMessage(update.EditedMessage)
+		return
+	} else if update.CallbackQuery != nil {
+		b.handleCallback(update.CallbackQuery)
+		return
+	} else if update.InlineQuery != nil {
+		b.handleInlineQuery(update.InlineQuery)
+		return
 	} else {
-		// Игнорируем другие типы обновлений (callback query, inline query и т.д.)
+		// Игнорируем другие типы обновлений
+		return
+	}
+
+	// Вступление новых участников (служебное сообщение без текста).
+	// Во время наплыва вступлений приветствия не отправляются, а при проверке (капче)
+	// приветствие откладывается до ее прохождения
+	if len(message.NewChatMembers) > 0 {
+		flooding := b.trackJoins(message)
+		// Участники, вступившие во время наплыва, уже ограничены JOIN_FLOOD_AUTO_MUTE
+		captcha := !(flooding && b.config.JoinFloodAutoMute) && b.startJoinCaptcha(message)
+		if !flooding && !captcha {
+			b.handleNewChatMembers(message)
+		}
+		return
+	}
+
+	// Сообщения без текста не сохраняются. Стикеры, фото, голосовые и видео без подписи
+	// только учитываются для случайного ответа и темпа чата (TRIGGER_COUNT_INCLUDES_MEDIA)
+	if storage.MessageText(message) == "" {
+		if isMediaOnly(message) {
+			b.handleMediaMessage(message)
+		}
 		return
 	}
 
-	// Игнорируем сообщения без текста или медиа с подписью
-	if message.Text == "" && message.Caption == "" {
+	// Защита от зацикливания: собственные сообщения бота (если они как-то вернулись в обновлениях)
+	// не обрабатываем вовсе, независимо от IGNORE_OTHER_BOTS
+	if message.From != nil && message.From.ID == b.botID {
+		log.Printf("[Bot WARN] Чат %d: Получено собственное сообщение бота %d, пропускаем.", message.Chat.ID, message.MessageID)
+		return
+	}
+
+	// Игнорируем сообщения других ботов и пользователей из IGNORED_USER_IDS
+	if b.isIgnoredSender(message.From) {
+		if b.config.Debug {
+			log.Printf("[DEBUG] Чат %d: Сообщение %d от игнорируемого отправителя %d пропущено.", message.Chat.ID, message.MessageID, message.From.ID)
+		}
 		return
 	}
 
@@ -129,25 +288,50 @@ func (b *Bot) handleUpdate(update tgbotapi.Update) {
 	userID := message.From.ID
 
 	// Логируем основную информацию о сообщении
-	log.Printf("[%d] %s (%d): %s", chatID, message.From.UserName, userID, truncateString(message.Text, 50))
+	log.Printf("[%d] %s (%d): %s", chatID, message.From.UserName, userID, truncateString(storage.MessageText(message), 50))
+
+	// Сообщения со словами из WORD_FILTER удаляются и не сохраняются
+	if b.applyWordFilter(message) {
+		return
+	}
+
+	// История чата должна быть в памяти до сохранения нового сообщения
+	b.ensureHistoryLoaded(chatID)
+
+	// Полный JSON (STORE_RAW_UPDATES) сериализуется из исходного сообщения, до очистки текста в хранилищах
+	var raw json.RawMessage
+	if b.config.StoreRawUpdates {
+		var err error
+		if raw, err = storage.RawMessageJSON(message); err != nil {
+			log.Printf("[WARN] Чат %d: Не удалось сериализовать сообщение %d целиком: %v", chatID, message.MessageID, err)
+		}
+	}
 
 	// --- Сохранение сообщения ---
 	go func(msgToSave *tgbotapi.Message) {
 		if msgToSave == nil {
 			return
 		}
-		b.storage.AddMessage(msgToSave.Chat.ID, msgToSave)
+		b.storage.AddMessageWithRaw(msgToSave.Chat.ID, msgToSave, raw)
 		log.Printf("[DEBUG] Сообщение %d от %d сохранено в основное хранилище для чата %d.", msgToSave.MessageID, msgToSave.From.ID, msgToSave.Chat.ID)
 
 		if b.localHistory != b.storage {
-			b.localHistory.AddMessage(msgToSave.Chat.ID, msgToSave)
+			b.localHistory.AddMessageWithRaw(msgToSave.Chat.ID, msgToSave, raw)
 			log.Printf("[DEBUG] Сообщение %d от %d сохранено в локальное хранилище для чата %d.", msgToSave.MessageID, msgToSave.From.ID, msgToSave.Chat.ID)
 		}
 	}(message)
 
 	// --- Обработка команд ---
-	if message.IsCommand() {
-		b.handleCommand(message)
+	if command, ok := b.commandMessage(message); ok {
+		b.handleCommand(command)
+		return
+	}
+
+	// Без LLM (ALLOW_LLMLESS_START) сообщения только сохраняются
+	if !b.llmAvailable() {
+		if b.config.Debug {
+			log.Printf("[DEBUG] Чат %d: LLM недоступна, ответ на сообщение %d пропущен.", chatID, message.MessageID)
+		}
 		return
 	}
 
@@ -169,6 +353,11 @@ func (b *Bot) handleUpdate(update tgbotapi.Update) {
 	}
 	repliedToBot := message.ReplyToMessage != nil && message.ReplyToMessage.From != nil && message.ReplyToMessage.From.ID == b.botID
 
+	// Пауза ответов (/mute_bot) или тихий режим чата (/bot_quiet): сообщения сохранены выше, но бот молчит
+	if !b.repliesEnabled() || !b.getChatSettingsSnapshot(chatID).RepliesEnabled {
+		return
+	}
+
 	if mentioned || repliedToBot {
 		b.handleDirectReply(message) // Обрабатываем как прямое обращение
 		return
@@ -178,43 +367,87 @@ func (b *Bot) handleUpdate(update tgbotapi.Update) {
 	settings := b.getChatSettings(chatID)
 	if settings.Active {
 		// Решаем, нужно ли отвечать (например, случайным образом или по другим условиям)
-		if shouldReply(message, b.config) {
+		if b.config.DiceReactions && isNotableDiceRoll(message.Dice) {
+			log.Printf("Чат %d: Выдающийся бросок %s %d, бот реагирует", chatID, message.Dice.Emoji, message.Dice.Value)
+			b.sendAIResponse(message)
+		} else if b.countsForTrigger(message) && shouldReply(message, b.config, b.replyChance(chatID)) {
 			b.sendAIResponse(message) // Отправляем ответ с использованием контекста
 		}
 	}
 }
 
-// handleCommand обрабатывает команды, адресованные боту.
+// ensureHistoryLoaded при LAZY_HISTORY_LOAD загружает историю чата из локального хранилища
+// при первом сообщении из него после старта.
+func (b *Bot) ensureHistoryLoaded(chatID int64) {
+	if !b.config.LazyHistoryLoad || b.localHistory == nil {
+		return
+	}
+	b.loadedChatsMutex.Lock()
+	defer b.loadedChatsMutex.Unlock()
+	if b.loadedChats[chatID] {
+		return
+	}
+	if _, err := b.localHistory.LoadChatHistory(chatID); err != nil {
+		log.Printf("[Bot WARN] Чат %d: Ошибка ленивой загрузки истории: %v", chatID, err)
+	}
+	// Помечаем чат загруженным и при ошибке, чтобы не повторять чтение на каждое сообщение
+	b.loadedChats[chatID] = true
+}
+
+// handleEditedMessage обновляет отредактированное сообщение в основном и локальном хранилищах,
+// чтобы история и долговременная память соответствовали тому, что видят участники.
+func (b *Bot) handleEditedMessage(message *tgbotapi.Message) {
+	if message.Chat == nil || storage.MessageText(message) == "" || b.isIgnoredSender(message.From) {
+		return
+	}
+	log.Printf("Получено отредактированное сообщение %d в чате %d", message.MessageID, message.Chat.ID)
+
+	go func(edited *tgbotapi.Message) {
+		b.storage.UpdateMessage(edited.Chat.ID, edited)
+		if b.localHistory != b.storage {
+			b.localHistory.UpdateMessage(edited.Chat.ID, edited)
+		}
+	}(message)
+}
+
+// handleCommand обрабатывает команды, адресованные боту: находит команду в botCommandList,
+// проверяет отключение в чате, права администратора и паузу ответов, затем вызывает обработчик.
+// При IGNORE_FOREIGN_COMMANDS команды других ботов (/cmd@OtherBot) и неизвестные команды в группах
+// пропускаются молча: в чате с несколькими ботами их обработает другой бот.
 func (b *Bot) handleCommand(message *tgbotapi.Message) {
 	chatID := message.Chat.ID
-
-	switch message.Command() {
-	case "start", "help":
-		helpMsg := b.config.HelpMessage
-		if helpMsg == "" {
-			helpMsg = "Привет! Я бот для чата. Команды: /activate, /deactivate, /status, /summarize, /srach [запрос]"
-		}
-		b.sendReply(chatID, helpMsg)
-	case "activate":
-		b.setChatActive(chatID, true)
-		b.sendReply(chatID, "Бот активирован для этого чата.")
-	case "deactivate":
-		b.setChatActive(chatID, false)
-		b.sendReply(chatID, "Бот деактивирован для этого чата.")
-	case "status":
-		settings := b.getChatSettings(chatID)
-		status := "неактивен"
-		if settings.Active {
-			status = "активен"
-		}
-		b.sendReply(chatID, fmt.Sprintf("Статус бота в этом чате: %s", status))
-	case "summarize":
-		b.handleSummarizeCommand(message)
-	case "srach": // Пример команды для поиска
-		b.handleSrachCommand(message)
-	default:
+	if b.config.IgnoreForeignCommands && b.isCommandForOtherBot(message) {
+		if b.config.Debug {
+			log.Printf("[DEBUG] Чат %d: Команда /%s адресована другому боту, пропускаем.", chatID, message.CommandWithAt())
+		}
+		return
+	}
+	command, ok := findBotCommand(message.Command())
+	if !ok {
+		if b.config.IgnoreForeignCommands && !message.Chat.IsPrivate() {
+			return
+		}
 		b.sendReply(chatID, "Неизвестная команда. Используйте /help для списка команд.")
+		return
 	}
+
+	if b.isCommandDisabled(chatID, command.name) {
+		b.sendReply(chatID, fmt.Sprintf("Команда /%s отключена в этом чате.", command.name))
+		return
+	}
+	if !b.canUseCommand(command, chatID, message.From) {
+		if command.chatAdminAllowed {
+			b.sendReply(chatID, "Эта команда доступна только администраторам чата и бота.")
+			return
+		}
+		b.sendReply(chatID, "Эта команда доступна только администраторам бота.")
+		return
+	}
+	if command.usesReplies && !b.repliesEnabled() {
+		b.sendReply(chatID, mutedReplyText)
+		return
+	}
+	command.handler(b, message)
 }
 
 // sendAIResponse генерирует и отправляет ответ AI на основе контекста чата.
@@ -246,44 +479,33 @@ func (b *Bot) sendAIResponse(message *tgbotapi.Message) {
 	}
 
 	// Формируем промпт для Gemini, включая саммари, если оно есть
-	prompt := b.config.BaseSystemPrompt
-	if prompt == "" {
-		prompt = "Ты - участник группового чата."
-	} // Дефолтный промпт
+	prompts := b.promptsForChat(chatID)
+	prompt := b.withMood(chatID, renderPrompt(prompts.BaseSystem, b.newPromptData(message, recentMessages)))
 	if summaryText != "" {
-		prompt += "\n\nВот краткое содержание предыдущего диалога (саммари):\n" + summaryText
-	}
-
-	// Объединяем историю сообщений
-	contextMessages := recentMessages
-
-	// Добавляем текущее сообщение в конец (конвертируем его)
-	currentMessageConverted := convertTgBotMessageToTypesMessage(message)
-	if currentMessageConverted != nil {
-		contextMessages = append(contextMessages, *currentMessageConverted)
+		prompt += "\n\n" + prompts.SummaryPrefix + "\n" + summaryText
 	}
 
-	// Сортируем по времени, чтобы гарантировать порядок
-	sort.SliceStable(contextMessages, func(i, j int) bool {
-		return contextMessages[i].Timestamp < contextMessages[j].Timestamp
-	})
-
-	// Оставляем только последние N сообщений для контекста, если их больше
-	if len(contextMessages) > b.config.MaxMessagesForContext {
-		contextMessages = contextMessages[len(contextMessages)-b.config.MaxMessagesForContext:]
-		log.Printf("Контекст для чата %d обрезан до %d сообщений", chatID, b.config.MaxMessagesForContext)
+	// Объединяем историю сообщений с текущим сообщением (без поиска по памяти),
+	// сортируем по времени и оставляем последние MaxMessagesForContext
+	// Медиа без подписи (handleMediaMessage) в контекст не добавляется: текста у него нет
+	var current *types.Message
+	if storage.MessageText(message) != "" {
+		current = convertTgBotMessageToTypesMessage(message)
 	}
+	contextMessages := b.assembleContext(chatID, nil, recentMessages, current)
 
 	log.Printf("Отправка AI запроса для чата %d с %d сообщениями в контексте...", chatID, len(contextMessages))
 
 	// Отправляем запрос в Gemini
 	geminiHistory := convertMessagesToGenaiContent(contextMessages)
 	lastMessageText := "" // Последнее сообщение уже включено в contextMessages
+	stopTyping := b.startTyping(chatID)
+	defer stopTyping()
 	ctxResp, cancelResp := context.WithTimeout(context.Background(), b.responseTimeout)
 	defer cancelResp()
 	var response string
 	var err error
-	response, err = b.gemini.GenerateContent(ctxResp, prompt, geminiHistory, lastMessageText, b.config.DefaultGenerationSettings)
+	response, err = b.generateContent(ctxResp, chatID, prompt, geminiHistory, lastMessageText, b.config.DefaultGenerationSettings)
 	if err != nil {
 		log.Printf("[ERROR] sendAIResponse: Ошибка генерации ответа от Gemini для чата %d: %v", chatID, err)
 		return
@@ -297,7 +519,7 @@ func (b *Bot) sendAIResponse(message *tgbotapi.Message) {
 func (b *Bot) handleDirectReply(message *tgbotapi.Message) {
 	chatID := message.Chat.ID
 	userID := message.From.ID
-	log.Printf("Получено прямое обращение от пользователя %d (%s) в чате %d: %s", userID, message.From.UserName, chatID, message.Text)
+	log.Printf("Получено прямое обращение от пользователя %d (%s) в чате %d: %s", userID, message.From.UserName, chatID, storage.MessageText(message))
 
 	// Получаем настройки чата
 	settings := b.getChatSettings(chatID)
@@ -325,11 +547,7 @@ func (b *Bot) handleDirectReply(message *tgbotapi.Message) {
 	if len(validTimestamps) >= b.config.DirectReplyLimitCount {
 		log.Printf("Превышен лимит прямых обращений для пользователя %d в чате %d. Игнорируем.", userID, chatID)
 		if len(validTimestamps) == b.config.DirectReplyLimitCount {
-			warningMsg := b.config.DirectReplyLimitPrompt
-			if warningMsg == "" {
-				warningMsg = "Вы слишком часто обращаетесь ко мне напрямую. Пожалуйста, подождите немного."
-			}
-			b.sendReplyToUser(chatID, message.MessageID, warningMsg)
+			b.sendReplyToUser(chatID, message.MessageID, b.promptsForChat(chatID).DirectReplyLimit)
 		}
 		b.directReplyMutex.Unlock()
 		return
@@ -359,68 +577,46 @@ func (b *Bot) handleDirectReply(message *tgbotapi.Message) {
 		}
 	}
 
-	// Ищем релевантные сообщения
+	// Ищем релевантные сообщения (если стратегия контекста использует память)
 	relevantMessages := []types.Message{}
-	// Вызываем FindRelevantMessages напрямую из интерфейса HistoryStorage
-	foundMessages, searchErr := b.storage.FindRelevantMessages(chatID, message.Text, b.config.RelevantMessagesCount)
-	if searchErr != nil {
-		// Обрабатываем ошибку поиска (но не прерываем выполнение, контекст все равно соберем)
-		log.Printf("Ошибка поиска релевантных сообщений для прямого ответа в чате %d: %v", chatID, searchErr)
-	} else {
-		relevantMessages = foundMessages
-		log.Printf("Найдено %d релевантных сообщений для прямого ответа в чате %d", len(relevantMessages), chatID)
+	if b.config.ContextStrategy != config.ContextStrategyRecentOnly {
+		// Вызываем FindRelevantMessages напрямую из интерфейса HistoryStorage
+		foundMessages, searchErr := b.storage.FindRelevantMessages(chatID, storage.MessageText(message), b.config.RelevantMessagesCount)
+		if searchErr != nil {
+			// Обрабатываем ошибку поиска (но не прерываем выполнение, контекст все равно соберем)
+			log.Printf("Ошибка поиска релевантных сообщений для прямого ответа в чате %d: %v", chatID, searchErr)
+			b.notifyAdmin("storage_search", "Ошибка поиска в хранилище %s (чат %d): %v", b.storage.BackendName(), chatID, searchErr)
+		} else {
+			relevantMessages = foundMessages
+			log.Printf("Найдено %d релевантных сообщений для прямого ответа в чате %d", len(relevantMessages), chatID)
+		}
 	}
 
 	// --- Формирование контекста и промпта ---
-	prompt := b.config.DirectReplyPrompt
-	if prompt == "" {
-		prompt = "Тебе адресовали сообщение:"
-	}
+	prompts := b.promptsForChat(chatID)
+	prompt := b.withMood(chatID, renderPrompt(prompts.DirectReply, b.newPromptData(message, recentMessages)))
 	if summaryText != "" {
-		prompt += "\n\nВот краткое содержание предыдущего диалога (саммари):\n" + summaryText
+		prompt += "\n\n" + prompts.SummaryPrefix + "\n" + summaryText
 	}
 
-	// Объединяем сообщения: релевантные + недавние + текущее
-	contextMessages := combineAndDeduplicateMessages(relevantMessages, recentMessages)
-
-	// Добавляем текущее сообщение
-	currentMessageConverted := convertTgBotMessageToTypesMessage(message)
-	if currentMessageConverted != nil {
-		found := false
-		for _, msg := range contextMessages {
-			if msg.ID == currentMessageConverted.ID && msg.ChatID == currentMessageConverted.ChatID {
-				found = true
-				break
-			}
-		}
-		if !found {
-			contextMessages = append(contextMessages, *currentMessageConverted)
-		}
-	}
+	// Объединяем сообщения: релевантные + недавние + текущее (согласно CONTEXT_STRATEGY)
+	contextMessages := b.assembleContext(chatID, relevantMessages, recentMessages, convertTgBotMessageToTypesMessage(message))
 
-	// Сортируем по времени
-	sort.SliceStable(contextMessages, func(i, j int) bool {
-		return contextMessages[i].Timestamp < contextMessages[j].Timestamp
-	})
-
-	// Ограничиваем общее количество сообщений для контекста
-	if len(contextMessages) > b.config.MaxMessagesForContext {
-		contextMessages = contextMessages[len(contextMessages)-b.config.MaxMessagesForContext:]
-		log.Printf("Контекст для прямого ответа в чате %d обрезан до %d сообщений", chatID, b.config.MaxMessagesForContext)
-	}
-
-	log.Printf("Отправка AI запроса для прямого ответа в чате %d с %d сообщениями в контексте...", chatID, len(contextMessages))
+	log.Printf("Отправка AI запроса для прямого ответа в чате %d с %d сообщениями в контексте (стратегия %s)...", chatID, len(contextMessages), b.config.ContextStrategy)
 
 	// --- Отправка запроса в Gemini ---
 	geminiHistory := convertMessagesToGenaiContent(contextMessages)
 	lastMessageText := ""
+	stopTyping := b.startTyping(chatID)
+	defer stopTyping()
 	ctxResp, cancelResp := context.WithTimeout(context.Background(), b.responseTimeout)
 	defer cancelResp()
 	var response string
 	var err error
-	response, err = b.gemini.GenerateContent(ctxResp, prompt, geminiHistory, lastMessageText, b.config.DefaultGenerationSettings)
+	response, err = b.generateContent(ctxResp, chatID, prompt, geminiHistory, lastMessageText, b.config.DefaultGenerationSettings)
 	if err != nil {
 		log.Printf("Ошибка генерации прямого ответа AI для чата %d: %v", chatID, err)
+		b.sendFallbackReply(chatID, message.MessageID)
 		return
 	}
 
@@ -434,38 +630,13 @@ func (b *Bot) handleSummarizeCommand(message *tgbotapi.Message) {
 	log.Printf("Получена команда /summarize в чате %d от пользователя %d", chatID, message.From.ID)
 
 	// --- Проверка кулдауна ---
-	b.summaryMutex.Lock()
-	lastReq, ok := b.lastSummaryRequest[chatID]
-	now := time.Now()
-	if ok && now.Sub(lastReq) < b.config.SummaryCooldown {
-		b.summaryMutex.Unlock()
-		log.Printf("Кулдаун команды /summarize для чата %d", chatID)
-		if now.Sub(lastReq) < b.config.SummaryCooldown-time.Second*5 {
-			prefix := b.config.SummaryRateLimitStaticPrefix
-			suffix := b.config.SummaryRateLimitStaticSuffix
-			insult := b.config.SummaryRateLimitInsultPrompt
-			if insult == "" {
-				insult = fmt.Sprintf("Команду /summarize можно использовать раз в %v. Пожалуйста, подождите.", b.config.SummaryCooldown)
-			}
-			b.sendReply(chatID, prefix+insult+suffix)
-		}
+	if !b.checkSummaryCooldown(chatID, "/summarize") {
 		return
 	}
-	b.lastSummaryRequest[chatID] = now
-	b.summaryMutex.Unlock()
 
 	// Получаем сообщения для саммаризации из основного хранилища
 	rawMessagesToSummarize := b.storage.GetMessages(chatID)
 	messagesToSummarize := convertTgMessagesToTypesMessages(rawMessagesToSummarize)
-	if messagesToSummarize == nil {
-		messagesToSummarize = []types.Message{}
-	}
-
-	// Применяем лимит MaxMessagesForSummary ПОСЛЕ получения
-	if len(messagesToSummarize) > b.config.MaxMessagesForSummary {
-		messagesToSummarize = messagesToSummarize[len(messagesToSummarize)-b.config.MaxMessagesForSummary:]
-		log.Printf("Сообщения для саммаризации в чате %d обрезаны до %d", chatID, b.config.MaxMessagesForSummary)
-	}
 
 	if len(messagesToSummarize) == 0 {
 		log.Printf("Нет сообщений для саммаризации в чате %d", chatID)
@@ -473,65 +644,25 @@ func (b *Bot) handleSummarizeCommand(message *tgbotapi.Message) {
 		return
 	}
 
-	log.Printf("Саммаризация %d сообщений для чата %d...", len(messagesToSummarize), chatID)
-
-	// Формируем контекст для Gemini (только сообщения)
-	contextMessages := messagesToSummarize
-
-	// Сортируем по времени
-	sort.SliceStable(contextMessages, func(i, j int) bool {
-		return contextMessages[i].Timestamp < contextMessages[j].Timestamp
-	})
-
-	// Отправляем запрос в Gemini для саммаризации
-	geminiHistory := convertMessagesToGenaiContent(contextMessages)
-	lastMessageText := ""
-	prompt := b.config.SummaryPrompt
-	if prompt == "" {
-		prompt = "Подведи итог этого диалога кратко:"
+	// Если новых сообщений с прошлого саммари не было, отдаем его из кеша, не тратя запрос к LLM
+	watermark := summaryWatermark(messagesToSummarize)
+	if cached, ok := b.getCachedSummary(chatID, watermark); ok {
+		log.Printf("Чат %d: новых сообщений с прошлого саммари нет, возвращаем кешированное.", chatID)
+		b.sendReply(chatID, "Новых сообщений не было, вот последнее саммари:\n\n"+cached)
+		return
 	}
-	ctxSummary, cancelSummary := context.WithTimeout(context.Background(), b.responseTimeout)
-	defer cancelSummary()
-	var response string
-	var err error
-	response, err = b.gemini.GenerateContent(ctxSummary, prompt, geminiHistory, lastMessageText, b.config.DefaultGenerationSettings)
+
+	response, err := b.generateSummary(chatID, message, messagesToSummarize)
 	if err != nil {
 		log.Printf("[Summary ERROR] Чат %d: Ошибка генерации саммари от Gemini: %v", chatID, err)
 		b.sendReply(chatID, "Не удалось сгенерировать саммари. Попробуйте позже.")
 		return
 	}
+	b.cacheSummary(chatID, watermark, response)
 
 	log.Printf("Саммари для чата %d сгенерировано: %s...", chatID, truncateString(response, 100))
 
-	// Сохраняем саммари в локальное хранилище
-	summaryInternalMessage := types.Message{
-		ID:        0,
-		ChatID:    chatID,
-		Text:      response,
-		Timestamp: int(time.Now().Unix()),
-		Role:      "summary",
-		UserID:    b.botID,
-		UserName:  b.api.Self.UserName,
-		FirstName: b.api.Self.FirstName,
-		IsBot:     true,
-	}
-	// Конвертируем types.Message обратно в *tgbotapi.Message для AddMessage
-	summaryTgMessage := &tgbotapi.Message{
-		MessageID: int(summaryInternalMessage.ID),
-		Chat:      &tgbotapi.Chat{ID: chatID},
-		From: &tgbotapi.User{
-			ID:        summaryInternalMessage.UserID,
-			IsBot:     summaryInternalMessage.IsBot,
-			FirstName: summaryInternalMessage.FirstName,
-			UserName:  summaryInternalMessage.UserName,
-		},
-		Date: summaryInternalMessage.Timestamp,
-		Text: summaryInternalMessage.Text,
-	}
-
-	// Вызываем AddMessage для локального хранилища без ожидания ошибки
-	b.localHistory.AddMessage(chatID, summaryTgMessage)
-	log.Printf("Саммари для чата %d сохранено в локальное хранилище.", chatID)
+	b.storeSummaryMessage(chatID, response)
 	b.sendReply(chatID, "Саммари обновлено!\n\n"+response)
 }
 
@@ -553,6 +684,7 @@ func (b *Bot) handleSrachCommand(message *tgbotapi.Message) {
 	foundMessages, searchErr := b.storage.FindRelevantMessages(chatID, query, b.config.SrachResultCount)
 	if searchErr != nil {
 		log.Printf("Ошибка поиска /srach в чате %d: %v", chatID, searchErr)
+		b.notifyAdmin("storage_search", "Ошибка поиска в хранилище %s (чат %d): %v", b.storage.BackendName(), chatID, searchErr)
 		b.sendReply(chatID, "Произошла ошибка при поиске сообщений.")
 		return
 	}
@@ -595,27 +727,101 @@ func (b *Bot) sendReply(chatID int64, text string) {
 		log.Printf("[WARNING] Попытка отправить пустое сообщение в чат %d", chatID)
 		return
 	}
-	msg := tgbotapi.NewMessage(chatID, text)
-	_, err := b.api.Send(msg)
-	if err != nil {
+	if err := b.sendText(chatID, 0, text); err != nil {
 		log.Printf("[ERROR] Не удалось отправить сообщение в чат %d: %v", chatID, err)
 	}
 }
 
 // sendReplyToUser отправляет сообщение в указанный чат как ответ на конкретное сообщение.
+// Если в настройках чата отключен UseReplyTo, сообщение отправляется без привязки к исходному.
 func (b *Bot) sendReplyToUser(chatID int64, replyToMessageID int, text string) {
 	if text == "" {
 		log.Printf("[WARNING] Попытка отправить пустое сообщение в чат %d (в ответ на %d)", chatID, replyToMessageID)
 		return
 	}
-	msg := tgbotapi.NewMessage(chatID, text)
-	msg.ReplyToMessageID = replyToMessageID
-	_, err := b.api.Send(msg)
-	if err != nil {
+	if !b.getChatSettingsSnapshot(chatID).UseReplyTo {
+		replyToMessageID = 0
+	}
+	if err := b.sendText(chatID, replyToMessageID, text); err != nil {
 		log.Printf("[ERROR] Не удалось отправить ответное сообщение в чат %d (на %d): %v", chatID, replyToMessageID, err)
 	}
 }
 
+// sendText отправляет текст, при необходимости разбивая его на части по лимиту Telegram.
+// Ответом на сообщение (replyToMessageID) делается только первая часть.
+// replyToMessageID = 0 означает отправку без ответа на сообщение.
+func (b *Bot) sendText(chatID int64, replyToMessageID int, text string) error {
+	chunks := splitMessage(text, splitChunkLimit)
+	if len(chunks) > 1 {
+		log.Printf("Чат %d: Длинное сообщение (%d символов) разбито на %d частей.", chatID, len([]rune(text)), len(chunks))
+	}
+	for i, chunk := range chunks {
+		replyTo := 0
+		if i == 0 {
+			replyTo = replyToMessageID
+		}
+		if err := b.sendTextChunk(chatID, replyTo, chunk); err != nil {
+			return fmt.Errorf("ошибка отправки части %d/%d: %w", i+1, len(chunks), err)
+		}
+	}
+	return nil
+}
+
+// sendTextChunk отправляет одну часть текста с разметкой Markdown (после санитизации).
+// Если Telegram не смог разобрать разметку, повторяет отправку как обычный текст.
+func (b *Bot) sendTextChunk(chatID int64, replyToMessageID int, text string) error {
+	sanitized := sanitizeMarkdown(text)
+	if len([]rune(sanitized)) > telegramMessageLimit {
+		// Экранирование раздуло текст сверх лимита - отправляем без разметки
+		plainMsg := tgbotapi.NewMessage(chatID, text)
+		plainMsg.ReplyToMessageID = replyToMessageID
+		_, err := b.send(chatID, plainMsg)
+		return err
+	}
+	msg := tgbotapi.NewMessage(chatID, sanitized)
+	msg.ParseMode = tgbotapi.ModeMarkdown
+	msg.ReplyToMessageID = replyToMessageID
+	_, err := b.send(chatID, msg)
+	if err == nil || !isMarkdownParseError(err) {
+		return err
+	}
+
+	log.Printf("[WARN] Чат %d: Telegram не разобрал Markdown (%v), повторяем отправку без разметки.", chatID, err)
+	plainMsg := tgbotapi.NewMessage(chatID, text)
+	plainMsg.ReplyToMessageID = replyToMessageID
+	_, err = b.send(chatID, plainMsg)
+	return err
+}
+
+// typingRefreshInterval - как часто обновлять статус "печатает" (Telegram сбрасывает его через ~5 секунд).
+const typingRefreshInterval = 4 * time.Second
+
+// startTyping показывает в чате статус "печатает" и обновляет его, пока не будет вызвана
+// возвращенная функция остановки. Используется на время генерации ответа LLM.
+func (b *Bot) startTyping(chatID int64) func() {
+	done := make(chan struct{})
+	var once sync.Once
+
+	go func() {
+		ticker := time.NewTicker(typingRefreshInterval)
+		defer ticker.Stop()
+		for {
+			if _, err := b.api.Request(tgbotapi.NewChatAction(chatID, tgbotapi.ChatTyping)); err != nil {
+				log.Printf("[WARN] Чат %d: Не удалось отправить статус 'печатает': %v", chatID, err)
+			}
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
+
 // getChatSettings возвращает настройки для чата, создавая их при необходимости.
 func (b *Bot) getChatSettings(chatID int64) *ChatSettings {
 	b.settingsMutex.RLock()
@@ -627,9 +833,7 @@ func (b *Bot) getChatSettings(chatID int64) *ChatSettings {
 		settings, exists = b.chatSettings[chatID]
 		if !exists {
 			log.Printf("Создание настроек по умолчанию для чата %d", chatID)
-			settings = &ChatSettings{
-				Active: b.config.ActivateNewChats,
-			}
+			settings = b.defaultChatSettings()
 			b.chatSettings[chatID] = settings
 		}
 		b.settingsMutex.Unlock()
@@ -637,12 +841,34 @@ func (b *Bot) getChatSettings(chatID int64) *ChatSettings {
 	return settings
 }
 
+// defaultChatSettings возвращает настройки нового чата.
+func (b *Bot) defaultChatSettings() *ChatSettings {
+	return &ChatSettings{
+		Active:             b.config.ActivateNewChats,
+		UseReplyTo:         true,
+		IncludeOwnMessages: true,
+		QuoteOfDayEnabled:  true,
+		StatsDigestEnabled: true,
+		RepliesEnabled:     true,
+		MoodsEnabled:       true,
+	}
+}
+
+// getChatSettingsSnapshot возвращает копию настроек чата, безопасную для чтения без блокировки.
+func (b *Bot) getChatSettingsSnapshot(chatID int64) ChatSettings {
+	settings := b.getChatSettings(chatID)
+	b.settingsMutex.RLock()
+	defer b.settingsMutex.RUnlock()
+	return *settings
+}
+
 // setChatActive устанавливает статус активности чата.
 func (b *Bot) setChatActive(chatID int64, active bool) {
 	settings := b.getChatSettings(chatID)
 	b.settingsMutex.Lock()
 	settings.Active = active
 	b.settingsMutex.Unlock()
+	b.scheduleChatSettingsSave(chatID)
 	status := "активирован"
 	if !active {
 		status = "деактивирован"
@@ -651,9 +877,10 @@ func (b *Bot) setChatActive(chatID int64, active bool) {
 }
 
 // shouldReply определяет, должен ли бот отвечать на данное сообщение.
-func shouldReply(message *tgbotapi.Message, cfg *config.Config) bool {
-	if cfg.RandomReplyEnabled && cfg.ReplyChance > 0 {
-		if rand.Float32() < cfg.ReplyChance {
+// chance - вероятность ответа (REPLY_CHANCE, с учетом ADAPTIVE_TRIGGER).
+func shouldReply(message *tgbotapi.Message, cfg *config.Config, chance float32) bool {
+	if cfg.RandomReplyEnabled && chance > 0 {
+		if rand.Float32() < chance {
 			log.Printf("Случайный ответ активирован для сообщения %d в чате %d", message.MessageID, message.Chat.ID)
 			return true
 		}
@@ -685,7 +912,7 @@ func combineAndDeduplicateMessages(slices ...[]types.Message) []types.Message {
 
 	for _, slice := range slices {
 		for _, msg := range slice {
-			key := fmt.Sprintf("%d:%d", msg.ChatID, msg.ID)
+			key := messageKey(msg)
 			if _, exists := seen[key]; !exists {
 				seen[key] = struct{}{}
 				result = append(result, msg)
@@ -695,6 +922,11 @@ func combineAndDeduplicateMessages(slices ...[]types.Message) []types.Message {
 	return result
 }
 
+// messageKey возвращает ключ сообщения для дедупликации.
+func messageKey(msg types.Message) string {
+	return fmt.Sprintf("%d:%d", msg.ChatID, msg.ID)
+}
+
 // --- Вспомогательные функции конвертации ---
 
 // convertTgBotMessageToTypesMessage конвертирует *tgbotapi.Message в types.Message
@@ -707,15 +939,10 @@ func convertTgBotMessageToTypesMessage(msg *tgbotapi.Message) *types.Message {
 		// TODO: Определять роль 'model' для сообщений нашего бота
 	}
 
-	text := msg.Text
-	if text == "" && msg.Caption != "" {
-		text = msg.Caption
-	}
-
 	converted := &types.Message{
 		ID:        int64(msg.MessageID),
 		ChatID:    msg.Chat.ID,
-		Text:      text,
+		Text:      storage.MessageText(msg), // Текст или подпись медиа
 		Timestamp: msg.Date,
 		Role:      role,
 	}
@@ -728,11 +955,10 @@ func convertTgBotMessageToTypesMessage(msg *tgbotapi.Message) *types.Message {
 	if msg.ReplyToMessage != nil {
 		converted.ReplyToMsgID = msg.ReplyToMessage.MessageID
 	}
-	if len(msg.Entities) > 0 {
-		converted.Entities = convertTgEntitiesToTypesEntities(msg.Entities)
-	} else if len(msg.CaptionEntities) > 0 {
-		converted.Entities = convertTgEntitiesToTypesEntities(msg.CaptionEntities)
+	if entities := storage.MessageEntities(msg); len(entities) > 0 {
+		converted.Entities = convertTgEntitiesToTypesEntities(entities)
 	}
+	converted.ForwardedFrom = storage.ForwardSource(msg)
 	return converted
 }
 
@@ -756,6 +982,7 @@ func convertMessagesToGenaiContent(messages []types.Message) []*genai.Content {
 	contents := make([]*genai.Content, 0, len(messages))
 	var lastRole string
 	for _, msg := range messages {
+		text := messageTextForLLM(msg)
 		role := "user"
 		if msg.IsBot {
 			// TODO: Более точно определять роль 'model' для сообщений нашего бота
@@ -769,18 +996,18 @@ func convertMessagesToGenaiContent(messages []types.Message) []*genai.Content {
 			lastContent := contents[len(contents)-1]
 			if len(lastContent.Parts) > 0 {
 				if textPart, ok := lastContent.Parts[len(lastContent.Parts)-1].(genai.Text); ok {
-					lastContent.Parts[len(lastContent.Parts)-1] = genai.Text(string(textPart) + "\n" + msg.Text)
+					lastContent.Parts[len(lastContent.Parts)-1] = genai.Text(string(textPart) + "\n" + text)
 				} else {
-					lastContent.Parts = append(lastContent.Parts, genai.Text(msg.Text))
+					lastContent.Parts = append(lastContent.Parts, genai.Text(text))
 				}
 			} else {
-				lastContent.Parts = append(lastContent.Parts, genai.Text(msg.Text))
+				lastContent.Parts = append(lastContent.Parts, genai.Text(text))
 			}
 			continue
 		}
 
 		contents = append(contents, &genai.Content{
-			Parts: []genai.Part{genai.Text(msg.Text)},
+			Parts: []genai.Part{genai.Text(text)},
 			Role:  role,
 		})
 		lastRole = role
@@ -794,6 +1021,15 @@ func convertMessagesToGenaiContent(messages []types.Message) []*genai.Content {
 	return contents
 }
 
+// messageTextForLLM возвращает текст сообщения для контекста LLM.
+// Пересланные сообщения помечаются, чтобы модель отличала их от слов самого участника.
+func messageTextForLLM(msg types.Message) string {
+	if msg.ForwardedFrom != "" {
+		return fmt.Sprintf("[переслано от %s] %s", msg.ForwardedFrom, msg.Text)
+	}
+	return msg.Text
+}
+
 // convertTgEntitiesToTypesEntities конвертирует []tgbotapi.MessageEntity в []types.MessageEntity
 func convertTgEntitiesToTypesEntities(tgEntities []tgbotapi.MessageEntity) []types.MessageEntity {
 	if tgEntities == nil {
@@ -0,0 +1,56 @@
+package bot
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// errLLMBudgetExceeded возвращается генерацией, если дневной лимит вызовов LLM исчерпан.
+var errLLMBudgetExceeded = errors.New("дневной лимит вызовов LLM исчерпан (LLM_DAILY_CALL_BUDGET)")
+
+// llmBudget считает вызовы LLM за текущие сутки в TIMEZONE (счетчик в памяти, обнуляется в полночь).
+type llmBudget struct {
+	mutex    sync.Mutex
+	location *time.Location
+	day      string // Дата текущих суток (YYYY-MM-DD в TIMEZONE)
+	calls    int
+}
+
+// newLLMBudget создает счетчик вызовов LLM для часового пояса timeZone.
+func newLLMBudget(timeZone string) *llmBudget {
+	loc, err := time.LoadLocation(timeZone)
+	if err != nil {
+		loc = time.UTC
+	}
+	return &llmBudget{location: loc}
+}
+
+// reserveLLMCall учитывает один запрос к LLM. Если LLM_DAILY_CALL_BUDGET задан и исчерпан,
+// возвращает errLLMBudgetExceeded и оповещает администратора (не чаще ADMIN_NOTIFY_COOLDOWN).
+func (b *Bot) reserveLLMCall() error {
+	limit := b.config.LLMDailyCallBudget
+	if limit <= 0 {
+		return nil
+	}
+
+	budget := b.llmBudget
+	budget.mutex.Lock()
+	today := time.Now().In(budget.location).Format("2006-01-02")
+	if budget.day != today {
+		if budget.day != "" {
+			log.Printf("[LLM] Новые сутки: за %s сделано %d вызовов LLM, счетчик обнулен.", budget.day, budget.calls)
+		}
+		budget.day = today
+		budget.calls = 0
+	}
+	if budget.calls >= limit {
+		budget.mutex.Unlock()
+		b.notifyAdmin("llm_budget", "Дневной лимит вызовов LLM (%d) исчерпан. Бот не будет генерировать ответы до полуночи (%s).", limit, budget.location)
+		return errLLMBudgetExceeded
+	}
+	budget.calls++
+	budget.mutex.Unlock()
+	return nil
+}
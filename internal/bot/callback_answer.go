@@ -0,0 +1,52 @@
+package bot
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// callbackAnswerTimeout - через сколько ответить на callback пустым ответом, если обработчик еще не ответил.
+// Пока ответа нет, Telegram показывает на кнопке индикатор загрузки.
+const callbackAnswerTimeout = 3 * time.Second
+
+// callbackAnswer отвечает на callback ровно один раз: текстом обработчика или пустым ответом по таймауту.
+type callbackAnswer struct {
+	bot      *Bot
+	callback *tgbotapi.CallbackQuery
+	once     sync.Once
+	timer    *time.Timer
+}
+
+// newCallbackAnswer создает ответ на callback и запускает таймер пустого ответа.
+// Вызывающий должен завершить обработку вызовом answer (повторные вызовы игнорируются).
+func (b *Bot) newCallbackAnswer(callback *tgbotapi.CallbackQuery) *callbackAnswer {
+	a := &callbackAnswer{bot: b, callback: callback}
+	a.timer = time.AfterFunc(callbackAnswerTimeout, func() {
+		log.Printf("[WARN] Обработка callback '%s' дольше %s, отвечаем без текста.", callback.Data, callbackAnswerTimeout)
+		a.answer("")
+	})
+	return a
+}
+
+// answer отвечает на callback текстом (пустой текст только убирает индикатор загрузки).
+func (a *callbackAnswer) answer(text string) {
+	a.send(tgbotapi.NewCallback(a.callback.ID, text))
+}
+
+// alert отвечает на callback всплывающим окном, которое пользователь должен закрыть (например, отказ в доступе).
+func (a *callbackAnswer) alert(text string) {
+	a.send(tgbotapi.NewCallbackWithAlert(a.callback.ID, text))
+}
+
+// send отправляет ответ на callback, если на него еще не ответили.
+func (a *callbackAnswer) send(config tgbotapi.CallbackConfig) {
+	a.once.Do(func() {
+		a.timer.Stop()
+		if _, err := a.bot.api.Request(config); err != nil {
+			log.Printf("[WARN] Не удалось ответить на callback '%s': %v", a.callback.Data, err)
+		}
+	})
+}
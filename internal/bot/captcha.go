@@ -0,0 +1,199 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// captchaCallbackPrefix - префикс callback кнопки проверки: captcha_<userID>.
+const captchaCallbackPrefix = "captcha_"
+
+// captchaMinRestriction - минимальный срок ограничения на время проверки. Ограничение короче 30 секунд
+// Telegram считает бессрочным, поэтому короткий JOIN_CAPTCHA_TIMEOUT дополняется до минуты.
+const captchaMinRestriction = time.Minute
+
+// pendingCaptcha - ожидающая проверка вступившего участника.
+type pendingCaptcha struct {
+	messageID int                // Сообщение с кнопкой проверки
+	cancel    context.CancelFunc // Отменяет таймер исключения при успешной проверке
+}
+
+// startJoinCaptcha запрещает вступившим участникам писать и просит нажать кнопку в течение
+// JOIN_CAPTCHA_TIMEOUT; не нажавшие исключаются из чата. Возвращает true, если проверка включена в чате.
+func (b *Bot) startJoinCaptcha(message *tgbotapi.Message) bool {
+	chatID := message.Chat.ID
+	if !b.getChatSettingsSnapshot(chatID).JoinCaptchaEnabled {
+		return false
+	}
+	for _, member := range message.NewChatMembers {
+		if member.IsBot {
+			continue
+		}
+		b.startCaptchaForMember(chatID, member)
+	}
+	return true
+}
+
+// startCaptchaForMember ограничивает участника и отправляет ему кнопку проверки.
+// Ожидающие проверки хранятся только в памяти, поэтому ограничение ставится со сроком (UntilDate):
+// если бот перезапустится до конца проверки, Telegram сам снимет его, и участник не останется без права писать.
+func (b *Bot) startCaptchaForMember(chatID int64, member tgbotapi.User) {
+	until := time.Now().Add(max(b.config.JoinCaptchaTimeout, captchaMinRestriction))
+	if err := b.restrictMember(chatID, member.ID, until); err != nil {
+		// Без прав администратора проверка бессмысленна: участник и так может писать
+		log.Printf("[Captcha WARN] Чат %d: Не удалось ограничить участника %d, проверка пропущена: %v", chatID, member.ID, err)
+		return
+	}
+
+	name := displayName(member.UserName, member.FirstName)
+	msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("%s, нажмите кнопку в течение %s, чтобы подтвердить, что вы не бот.", name, b.config.JoinCaptchaTimeout))
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅ Я не бот", captchaCallbackPrefix+strconv.FormatInt(member.ID, 10)),
+		),
+	)
+	sent, err := b.send(chatID, msg)
+	if err != nil {
+		log.Printf("[Captcha ERROR] Чат %d: Не удалось отправить проверку участнику %d: %v", chatID, member.ID, err)
+		b.liftCaptchaRestriction(chatID, member.ID)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	b.captchaMutex.Lock()
+	if b.pendingCaptchas[chatID] == nil {
+		b.pendingCaptchas[chatID] = make(map[int64]*pendingCaptcha)
+	}
+	if previous, ok := b.pendingCaptchas[chatID][member.ID]; ok {
+		// Повторное вступление: прежняя проверка заменяется новой
+		previous.cancel()
+	}
+	b.pendingCaptchas[chatID][member.ID] = &pendingCaptcha{messageID: sent.MessageID, cancel: cancel}
+	b.captchaMutex.Unlock()
+	log.Printf("[Captcha] Чат %d: Участник %d должен пройти проверку за %s.", chatID, member.ID, b.config.JoinCaptchaTimeout)
+
+	go b.waitCaptcha(ctx, chatID, member.ID)
+}
+
+// waitCaptcha исключает участника, не прошедшего проверку за JOIN_CAPTCHA_TIMEOUT.
+func (b *Bot) waitCaptcha(ctx context.Context, chatID, userID int64) {
+	timer := time.NewTimer(b.config.JoinCaptchaTimeout)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		return
+	case <-b.stop:
+		return // Ограничение снимется само по истечении срока
+	}
+
+	pending := b.takePendingCaptcha(chatID, userID)
+	if pending == nil {
+		return
+	}
+	b.deleteMessage(chatID, pending.messageID)
+
+	// Исключение = бан и сразу разбан: участник сможет вступить повторно
+	member := tgbotapi.ChatMemberConfig{ChatID: chatID, UserID: userID}
+	if _, err := b.api.Request(tgbotapi.BanChatMemberConfig{ChatMemberConfig: member}); err != nil {
+		log.Printf("[Captcha WARN] Чат %d: Не удалось исключить участника %d: %v", chatID, userID, err)
+		return
+	}
+	if _, err := b.api.Request(tgbotapi.UnbanChatMemberConfig{ChatMemberConfig: member, OnlyIfBanned: true}); err != nil {
+		log.Printf("[Captcha WARN] Чат %d: Не удалось разбанить исключенного участника %d: %v", chatID, userID, err)
+	}
+	log.Printf("[Captcha] Чат %d: Участник %d не прошел проверку и исключен.", chatID, userID)
+}
+
+// handleCaptchaCallback обрабатывает нажатие кнопки проверки. Нажать ее может только сам вступивший.
+func (b *Bot) handleCaptchaCallback(callback *tgbotapi.CallbackQuery, answer *callbackAnswer) {
+	chatID := callback.Message.Chat.ID
+	answerText := "Проверка пройдена, добро пожаловать!"
+	userID, err := strconv.ParseInt(strings.TrimPrefix(callback.Data, captchaCallbackPrefix), 10, 64)
+	switch {
+	case err != nil:
+		answerText = ""
+	case callback.From == nil || callback.From.ID != userID:
+		answerText = "Эта проверка не для вас."
+	default:
+		if pending := b.takePendingCaptcha(chatID, userID); pending != nil {
+			pending.cancel()
+			// Отвечаем сразу: снятие ограничений и приветствие (возможно, через LLM) занимают время
+			answer.answer(answerText)
+			b.deleteMessage(chatID, pending.messageID)
+			b.liftCaptchaRestriction(chatID, userID)
+			log.Printf("[Captcha] Чат %d: Участник %d прошел проверку.", chatID, userID)
+			// Приветствие откладывается до прохождения проверки
+			b.handleNewChatMembers(&tgbotapi.Message{Chat: callback.Message.Chat, NewChatMembers: []tgbotapi.User{*callback.From}})
+		} else {
+			// Проверка уже пройдена, истекла или потеряна при перезапуске. Права не трогаем: после проверки
+			// администратор мог ограничить участника, а ограничение капчи снимется само по истечении срока
+			answerText = "Эта проверка больше не действует."
+			b.deleteMessage(chatID, callback.Message.MessageID)
+			log.Printf("[Captcha] Чат %d: Участник %d нажал кнопку устаревшей проверки.", chatID, userID)
+		}
+	}
+
+	answer.answer(answerText)
+}
+
+// takePendingCaptcha извлекает ожидающую проверку участника (nil, если ее нет).
+func (b *Bot) takePendingCaptcha(chatID, userID int64) *pendingCaptcha {
+	b.captchaMutex.Lock()
+	defer b.captchaMutex.Unlock()
+	pending, ok := b.pendingCaptchas[chatID][userID]
+	if !ok {
+		return nil
+	}
+	delete(b.pendingCaptchas[chatID], userID)
+	return pending
+}
+
+// restrictMember запрещает участнику писать в чате до until.
+func (b *Bot) restrictMember(chatID, userID int64, until time.Time) error {
+	restrict := tgbotapi.RestrictChatMemberConfig{
+		ChatMemberConfig: tgbotapi.ChatMemberConfig{ChatID: chatID, UserID: userID},
+		UntilDate:        until.Unix(),
+		Permissions:      &tgbotapi.ChatPermissions{},
+	}
+	_, err := b.api.Request(restrict)
+	return err
+}
+
+// restoreMemberPermissions возвращает участнику права по умолчанию самого чата (getChat().Permissions),
+// а не фиксированный набор: иначе участник получил бы больше, чем разрешено в чате остальным.
+func (b *Bot) restoreMemberPermissions(chatID, userID int64) error {
+	chat, err := b.api.GetChat(tgbotapi.ChatInfoConfig{ChatConfig: tgbotapi.ChatConfig{ChatID: chatID}})
+	if err != nil {
+		return fmt.Errorf("не удалось получить права чата по умолчанию: %w", err)
+	}
+	if chat.Permissions == nil {
+		return fmt.Errorf("Telegram не вернул права чата по умолчанию")
+	}
+	restrict := tgbotapi.RestrictChatMemberConfig{
+		ChatMemberConfig: tgbotapi.ChatMemberConfig{ChatID: chatID, UserID: userID},
+		Permissions:      chat.Permissions,
+	}
+	_, err = b.api.Request(restrict)
+	return err
+}
+
+// liftCaptchaRestriction снимает ограничение проверки. При ошибке ограничение снимется само по истечении срока.
+func (b *Bot) liftCaptchaRestriction(chatID, userID int64) {
+	if err := b.restoreMemberPermissions(chatID, userID); err != nil {
+		log.Printf("[Captcha WARN] Чат %d: Не удалось снять ограничения с участника %d (снимутся по истечении срока): %v", chatID, userID, err)
+	}
+}
+
+// deleteMessage удаляет сообщение бота из чата, ошибки только логируются.
+func (b *Bot) deleteMessage(chatID int64, messageID int) {
+	if _, err := b.api.Request(tgbotapi.NewDeleteMessage(chatID, messageID)); err != nil {
+		log.Printf("[WARN] Чат %d: Не удалось удалить сообщение %d: %v", chatID, messageID, err)
+	}
+}
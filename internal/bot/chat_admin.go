@@ -0,0 +1,71 @@
+package bot
+
+import (
+	"log"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// chatAdminsTTL - сколько хранится список администраторов чата, чтобы не запрашивать
+// getChatAdministrators на каждую команду.
+const chatAdminsTTL = 5 * time.Minute
+
+// chatAdmins - закешированный результат getChatAdministrators.
+type chatAdmins struct {
+	userIDs   map[int64]bool
+	checkedAt time.Time
+}
+
+// isChatAdmin проверяет, является ли пользователь администратором (или создателем) Telegram-чата.
+// В личных чатах администраторов нет. При ошибке запроса используется прежний список, если он есть.
+func (b *Bot) isChatAdmin(chatID, userID int64) bool {
+	if chatID == userID {
+		return false // Личный чат пользователя с ботом
+	}
+	b.chatAdminsMutex.Lock()
+	cached, ok := b.chatAdminsCache[chatID]
+	b.chatAdminsMutex.Unlock()
+	if ok && time.Since(cached.checkedAt) < chatAdminsTTL {
+		return cached.userIDs[userID]
+	}
+
+	members, err := b.api.GetChatAdministrators(tgbotapi.ChatAdministratorsConfig{
+		ChatConfig: tgbotapi.ChatConfig{ChatID: chatID},
+	})
+	if err != nil {
+		log.Printf("[ChatAdmin WARN] Чат %d: Не удалось получить администраторов: %v", chatID, err)
+		return ok && cached.userIDs[userID]
+	}
+	userIDs := make(map[int64]bool, len(members))
+	for _, member := range members {
+		if member.User != nil {
+			userIDs[member.User.ID] = true
+		}
+	}
+
+	b.chatAdminsMutex.Lock()
+	b.chatAdminsCache[chatID] = chatAdmins{userIDs: userIDs, checkedAt: time.Now()}
+	b.chatAdminsMutex.Unlock()
+	return userIDs[userID]
+}
+
+// isChatOrBotAdmin проверяет, является ли пользователь администратором бота или администратором Telegram-чата.
+func (b *Bot) isChatOrBotAdmin(chatID, userID int64) bool {
+	return b.isAdmin(userID) || b.isChatAdmin(chatID, userID)
+}
+
+// canUseCommand проверяет права на команду: команды adminOnly доступны администраторам бота,
+// а с chatAdminAllowed - еще и администраторам самого чата.
+func (b *Bot) canUseCommand(command botCommand, chatID int64, from *tgbotapi.User) bool {
+	if !command.adminOnly {
+		return true
+	}
+	if from == nil {
+		return false
+	}
+	if command.chatAdminAllowed {
+		return b.isChatOrBotAdmin(chatID, from.ID)
+	}
+	return b.isAdmin(from.ID)
+}
@@ -0,0 +1,190 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// botCommand - команда бота: обработчик и метаданные для диспетчера handleCommand и /help.
+type botCommand struct {
+	name        string // Имя без "/"
+	args        string // Подсказка по аргументам, например "<период>"
+	description string // Описание для /help; пустое - команда не показывается
+	adminOnly   bool   // Только для администраторов бота (ADMIN_USER_IDS)
+	// chatAdminAllowed - команду adminOnly могут вызывать и администраторы самого Telegram-чата.
+	// Не ставится для команд, действующих на всего бота (пауза ответов, статистика Qdrant, модель).
+	chatAdminAllowed bool
+	usesReplies      bool // Генерирует ответ через LLM и недоступна при паузе ответов (/mute_bot)
+	handler          func(b *Bot, message *tgbotapi.Message)
+}
+
+// botCommands - все команды бота в порядке показа в /help, botCommandsByName - они же по имени.
+// Заполняются в init: обработчики (например, /help) сами обращаются к списку команд, и прямая
+// инициализация переменной была бы циклом инициализации.
+var (
+	botCommands       []botCommand
+	botCommandsByName map[string]botCommand
+)
+
+func init() {
+	botCommands = botCommandList()
+	botCommandsByName = make(map[string]botCommand, len(botCommands))
+	for _, command := range botCommands {
+		botCommandsByName[command.name] = command
+	}
+}
+
+// botCommandList возвращает все команды бота в порядке показа в /help.
+// Вызывается один раз при инициализации пакета, дальше используется botCommands.
+func botCommandList() []botCommand {
+	return []botCommand{
+		{name: "start", handler: (*Bot).handleStartCommand},
+		{name: "help", description: "список команд и настройки чата", handler: (*Bot).handleHelpCommand},
+		{name: "activate", description: "включить бота в чате", handler: func(b *Bot, message *tgbotapi.Message) { b.handleActivateCommand(message, true) }},
+		{name: "deactivate", description: "выключить бота в чате", handler: func(b *Bot, message *tgbotapi.Message) { b.handleActivateCommand(message, false) }},
+		{name: "status", description: "статус бота в чате", handler: (*Bot).handleStatusCommand},
+		{name: "settings", description: "меню настроек чата", handler: func(b *Bot, message *tgbotapi.Message) { b.sendSettingsMenu(message.Chat.ID) }},
+		{name: "summarize", description: "саммари недавнего разговора", usesReplies: true, handler: (*Bot).handleSummarizeCommand},
+		{name: "summary_since", args: "<период>", description: "саммари за период, например 3h", usesReplies: true, handler: (*Bot).handleSummarySinceCommand},
+		{name: "srach", args: "[запрос]", description: "поиск по истории", handler: (*Bot).handleSrachCommand},
+		{name: "random", description: "случайное сообщение из истории", handler: (*Bot).handleRandomCommand},
+		{name: "stats", args: "[период]", description: "статистика чата", handler: (*Bot).handleStatsCommand},
+		{name: "rules", description: "правила чата", handler: (*Bot).handleRulesCommand},
+		{name: "remind", args: "<через сколько> <текст>", description: "поставить напоминание", handler: (*Bot).handleRemindCommand},
+		{name: "reminders", description: "напоминания чата", handler: (*Bot).handleRemindersCommand},
+		{name: "cancelremind", args: "<номер>", description: "отменить свое напоминание", handler: (*Bot).handleCancelRemindCommand},
+		{name: "delete", description: "(ответом на сообщение) удалить свое сообщение из памяти", handler: (*Bot).handleDeleteCommand},
+		{name: "forget_user", args: "[@username]", description: "удалить историю пользователя", adminOnly: true, chatAdminAllowed: true, handler: (*Bot).handleForgetUserCommand},
+		{name: "raw", description: "(ответом на сообщение) сохраненный JSON сообщения", adminOnly: true, handler: (*Bot).handleRawCommand},
+		{name: "qdrant_stats", description: "статистика долговременной памяти", adminOnly: true, handler: (*Bot).handleQdrantStatsCommand},
+		{name: "setlang", args: "<язык>", description: "язык промптов чата", adminOnly: true, chatAdminAllowed: true, handler: (*Bot).handleSetLangCommand},
+		{name: "setmodel", args: "<модель>", description: "модель генерации чата", adminOnly: true, handler: (*Bot).handleSetModelCommand},
+		{name: "setrules", args: "<текст>", description: "задать правила чата", adminOnly: true, chatAdminAllowed: true, handler: (*Bot).handleSetRulesCommand},
+		{name: "bot_quiet", description: "тихий режим в чате", adminOnly: true, chatAdminAllowed: true, handler: func(b *Bot, message *tgbotapi.Message) { b.handleChatRepliesCommand(message, false) }},
+		{name: "bot_talk", description: "выключить тихий режим", adminOnly: true, chatAdminAllowed: true, handler: func(b *Bot, message *tgbotapi.Message) { b.handleChatRepliesCommand(message, true) }},
+		{name: "disable_cmd", args: "<команда>", description: "отключить команду в чате", adminOnly: true, chatAdminAllowed: true, handler: func(b *Bot, message *tgbotapi.Message) { b.handleDisableCommandCommand(message, true) }},
+		{name: "enable_cmd", args: "<команда>", description: "включить команду в чате", adminOnly: true, chatAdminAllowed: true, handler: func(b *Bot, message *tgbotapi.Message) { b.handleDisableCommandCommand(message, false) }},
+		{name: "mute_bot", description: "приостановить ответы во всех чатах", adminOnly: true, handler: func(b *Bot, message *tgbotapi.Message) { b.handleMuteCommand(message, true) }},
+		{name: "unmute_bot", description: "возобновить ответы", adminOnly: true, handler: func(b *Bot, message *tgbotapi.Message) { b.handleMuteCommand(message, false) }},
+	}
+}
+
+// findBotCommand ищет команду по имени (без учета регистра).
+func findBotCommand(name string) (botCommand, bool) {
+	command, ok := botCommandsByName[strings.ToLower(name)]
+	return command, ok
+}
+
+// commandMessage возвращает сообщение как команду: обычные "/команды" как есть, а текст с префиксом
+// COMMAND_PREFIX (например, "!summarize") - копией с "/" и сущностью bot_command, чтобы обработчики
+// работали с Command() и CommandArguments() одинаково. С префиксом распознаются только известные команды.
+func (b *Bot) commandMessage(message *tgbotapi.Message) (*tgbotapi.Message, bool) {
+	if message.IsCommand() {
+		return message, true
+	}
+	prefix := b.config.CommandPrefix
+	if prefix == "" || !strings.HasPrefix(message.Text, prefix) {
+		return nil, false
+	}
+	text := "/" + strings.TrimPrefix(message.Text, prefix)
+	word := strings.Fields(text)[0]
+	if _, ok := findBotCommand(normalizeCommandName(word)); !ok {
+		return nil, false
+	}
+
+	command := *message
+	command.Text = text
+	// Длина в байтах: так ее использует tgbotapi в CommandWithAt и CommandArguments
+	command.Entities = []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: len(word)}}
+	return &command, true
+}
+
+// isCommandForOtherBot проверяет, адресована ли команда другому боту (/summary@OtherBot).
+func (b *Bot) isCommandForOtherBot(message *tgbotapi.Message) bool {
+	command := message.CommandWithAt()
+	at := strings.Index(command, "@")
+	if at < 0 {
+		return false
+	}
+	return !strings.EqualFold(command[at+1:], b.api.Self.UserName)
+}
+
+// handleStartCommand показывает приветствие HELP_MESSAGE.
+func (b *Bot) handleStartCommand(message *tgbotapi.Message) {
+	helpMsg := b.config.HelpMessage
+	if helpMsg == "" {
+		helpMsg = "Привет! Я бот для чата. Команды: /activate, /deactivate, /status, /settings, /summarize, /summary_since [период], /srach [запрос], /random, /stats [период]"
+	}
+	b.sendReply(message.Chat.ID, helpMsg)
+}
+
+// handleActivateCommand обрабатывает /activate и /deactivate.
+func (b *Bot) handleActivateCommand(message *tgbotapi.Message, active bool) {
+	b.setChatActive(message.Chat.ID, active)
+	if active {
+		b.sendReply(message.Chat.ID, "Бот активирован для этого чата.")
+		return
+	}
+	b.sendReply(message.Chat.ID, "Бот деактивирован для этого чата.")
+}
+
+// handleStatusCommand показывает, активен ли бот в чате и не приостановлены ли ответы.
+func (b *Bot) handleStatusCommand(message *tgbotapi.Message) {
+	settings := b.getChatSettingsSnapshot(message.Chat.ID)
+	status := activeLabel(settings.Active)
+	if !b.repliesEnabled() {
+		status += " (ответы приостановлены администратором)"
+	} else if !settings.RepliesEnabled {
+		status += " (тихий режим: бот не отвечает в этом чате)"
+	}
+	b.sendReply(message.Chat.ID, fmt.Sprintf("Статус бота в этом чате: %s", status))
+}
+
+// handleHelpCommand показывает доступные пользователю команды (без недоступных ему команд
+// администраторов и без отключенных в чате) и текущие настройки чата.
+func (b *Bot) handleHelpCommand(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+
+	var sb strings.Builder
+	sb.WriteString("📖 Команды:\n")
+	for _, command := range botCommands {
+		if command.description == "" || !b.canUseCommand(command, chatID, message.From) || b.isCommandDisabled(chatID, command.name) {
+			continue
+		}
+		line := "/" + command.name
+		if command.args != "" {
+			line += " " + command.args
+		}
+		sb.WriteString(fmt.Sprintf("%s - %s\n", line, command.description))
+	}
+
+	settings := b.getChatSettingsSnapshot(chatID)
+	language := settings.Language
+	if language == "" {
+		language = defaultLanguage
+	}
+	memory := "выкл"
+	if b.storage.SupportsVectorSearch() {
+		memory = "вкл (" + b.storage.BackendName() + ")"
+	}
+	sb.WriteString("\n⚙️ Настройки чата:\n")
+	sb.WriteString(fmt.Sprintf("Бот: %s, ответы: %s\n", activeLabel(settings.Active), onOffLabel(settings.RepliesEnabled && b.repliesEnabled())))
+	sb.WriteString(fmt.Sprintf("Шанс случайного ответа: %.0f%%\n", b.replyChance(chatID)*100))
+	if b.llmAvailable() {
+		sb.WriteString(fmt.Sprintf("Модель: %s, язык: %s\n", b.modelForChat(chatID), language))
+	}
+	sb.WriteString(fmt.Sprintf("Случайное настроение: %s\n", onOffLabel(settings.MoodsEnabled && len(b.config.PersonaMoods) > 0)))
+	sb.WriteString(fmt.Sprintf("Фильтр слов: %s, проверка новичков: %s\n", onOffLabel(settings.WordFilterEnabled && len(b.config.WordFilter) > 0), onOffLabel(settings.JoinCaptchaEnabled)))
+	sb.WriteString(fmt.Sprintf("Долговременная память: %s", memory))
+	b.sendReply(chatID, sb.String())
+}
+
+// activeLabel возвращает подпись активности бота в чате.
+func activeLabel(active bool) string {
+	if active {
+		return "активен"
+	}
+	return "неактивен"
+}
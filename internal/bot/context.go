@@ -0,0 +1,102 @@
+package bot
+
+import (
+	"sort"
+
+	"github.com/Henry-Case-dev/rofloslav/internal/config"
+	"github.com/Henry-Case-dev/rofloslav/internal/types"
+)
+
+// assembleContext собирает итоговый контекст для LLM из найденных в долговременной памяти
+// сообщений (relevant), недавней истории (recent) и текущего сообщения (current, может быть nil).
+// Способ объединения задается CONTEXT_STRATEGY:
+//   - recent_only: память не используется, только недавние сообщения;
+//   - memory_prefix: сначала блок из памяти (по времени), затем недавние сообщения.
+//     Оба блока вместе не больше MAX_MESSAGES_FOR_CONTEXT; память вытесняет недавнюю историю,
+//     кроме текущего сообщения;
+//   - interleaved: все сообщения перемешиваются по времени, лимит оставляет самые свежие.
+//     Старые воспоминания при этом могут быть отброшены.
+//
+// Сообщения, присутствующие и в памяти, и в недавней истории, попадают в контекст один раз.
+// Если в настройках чата отключен IncludeOwnMessages, сообщения самого бота исключаются.
+func (b *Bot) assembleContext(chatID int64, relevant, recent []types.Message, current *types.Message) []types.Message {
+	limit := b.config.MaxMessagesForContext
+
+	if !b.getChatSettingsSnapshot(chatID).IncludeOwnMessages {
+		relevant = b.excludeOwnMessages(relevant)
+		recent = b.excludeOwnMessages(recent)
+	}
+
+	if current != nil {
+		recent = combineAndDeduplicateMessages(recent, []types.Message{*current})
+	}
+	sortByTimestamp(recent)
+
+	switch b.config.ContextStrategy {
+	case config.ContextStrategyRecentOnly:
+		return lastMessages(recent, limit)
+
+	case config.ContextStrategyMemoryPrefix:
+		// Убираем из памяти то, что и так есть в недавней истории
+		memory := excludeMessages(relevant, recent)
+		sortByTimestamp(memory)
+		if limit <= 0 {
+			return append(memory, recent...)
+		}
+		// Блок памяти входит в лимит MAX_MESSAGES_FOR_CONTEXT, одно место остается за текущим сообщением
+		memoryLimit := limit
+		if len(recent) > 0 {
+			memoryLimit = limit - 1
+		}
+		if len(memory) > memoryLimit {
+			memory = memory[len(memory)-memoryLimit:]
+		}
+		return append(memory, recent[max(len(recent)-(limit-len(memory)), 0):]...)
+
+	default: // config.ContextStrategyInterleaved
+		combined := combineAndDeduplicateMessages(relevant, recent)
+		sortByTimestamp(combined)
+		return lastMessages(combined, limit)
+	}
+}
+
+// excludeOwnMessages убирает из среза сообщения, отправленные самим ботом.
+func (b *Bot) excludeOwnMessages(messages []types.Message) []types.Message {
+	result := make([]types.Message, 0, len(messages))
+	for _, msg := range messages {
+		if msg.UserID != b.botID {
+			result = append(result, msg)
+		}
+	}
+	return result
+}
+
+// sortByTimestamp сортирует сообщения по времени отправки.
+func sortByTimestamp(messages []types.Message) {
+	sort.SliceStable(messages, func(i, j int) bool {
+		return messages[i].Timestamp < messages[j].Timestamp
+	})
+}
+
+// lastMessages возвращает последние limit сообщений (limit <= 0 - без ограничения).
+func lastMessages(messages []types.Message, limit int) []types.Message {
+	if limit > 0 && len(messages) > limit {
+		return messages[len(messages)-limit:]
+	}
+	return messages
+}
+
+// excludeMessages возвращает сообщения из source, которых нет в exclude.
+func excludeMessages(source, exclude []types.Message) []types.Message {
+	excluded := make(map[string]struct{}, len(exclude))
+	for _, msg := range exclude {
+		excluded[messageKey(msg)] = struct{}{}
+	}
+	result := make([]types.Message, 0, len(source))
+	for _, msg := range source {
+		if _, ok := excluded[messageKey(msg)]; !ok {
+			result = append(result, msg)
+		}
+	}
+	return result
+}
@@ -0,0 +1,97 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/Henry-Case-dev/rofloslav/internal/config"
+	"github.com/Henry-Case-dev/rofloslav/internal/types"
+)
+
+func TestCombineAndDeduplicateMessages(t *testing.T) {
+	msg := func(chatID, id int64) types.Message {
+		return types.Message{ChatID: chatID, ID: id, Text: "сообщение"}
+	}
+	tests := []struct {
+		name   string
+		slices [][]types.Message
+		want   []string // Ключи messageKey в ожидаемом порядке
+	}{
+		{
+			name:   "память пересекается с недавней историей",
+			slices: [][]types.Message{{msg(1, 10), msg(1, 11)}, {msg(1, 11), msg(1, 12)}},
+			want:   []string{"1:10", "1:11", "1:12"},
+		},
+		{
+			name:   "одинаковый ID в разных чатах",
+			slices: [][]types.Message{{msg(1, 10)}, {msg(2, 10)}},
+			want:   []string{"1:10", "2:10"},
+		},
+		{
+			name:   "дубликаты внутри одного среза",
+			slices: [][]types.Message{{msg(1, 10), msg(1, 10)}},
+			want:   []string{"1:10"},
+		},
+		{
+			name:   "пустые срезы",
+			slices: [][]types.Message{nil, {}},
+			want:   []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := combineAndDeduplicateMessages(tt.slices...)
+			if len(got) != len(tt.want) {
+				t.Fatalf("len(combineAndDeduplicateMessages) = %d, want %d", len(got), len(tt.want))
+			}
+			for i, m := range got {
+				if key := messageKey(m); key != tt.want[i] {
+					t.Errorf("combineAndDeduplicateMessages[%d] = %s, want %s", i, key, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestExcludeMessages(t *testing.T) {
+	source := []types.Message{{ChatID: 1, ID: 10}, {ChatID: 1, ID: 11}, {ChatID: 2, ID: 10}}
+	got := excludeMessages(source, []types.Message{{ChatID: 1, ID: 10}})
+	want := []string{"1:11", "2:10"}
+	if len(got) != len(want) {
+		t.Fatalf("len(excludeMessages) = %d, want %d", len(got), len(want))
+	}
+	for i, m := range got {
+		if key := messageKey(m); key != want[i] {
+			t.Errorf("excludeMessages[%d] = %s, want %s", i, key, want[i])
+		}
+	}
+}
+
+func TestAssembleContextLimit(t *testing.T) {
+	const limit = 5
+	messages := func(from, count int) []types.Message {
+		result := make([]types.Message, 0, count)
+		for id := from; id < from+count; id++ {
+			result = append(result, types.Message{ChatID: 1, ID: int64(id), Timestamp: id, Text: "сообщение"})
+		}
+		return result
+	}
+	current := types.Message{ChatID: 1, ID: 1000, Timestamp: 1000, Text: "текущее"}
+
+	for _, strategy := range []string{config.ContextStrategyRecentOnly, config.ContextStrategyMemoryPrefix, config.ContextStrategyInterleaved} {
+		t.Run(strategy, func(t *testing.T) {
+			b := &Bot{
+				config:       &config.Config{MaxMessagesForContext: limit, ContextStrategy: strategy},
+				chatSettings: make(map[int64]*ChatSettings),
+			}
+			// Памяти больше лимита: она не должна вытеснить текущее сообщение или превысить лимит
+			got := b.assembleContext(1, messages(1, limit*2), messages(100, limit), &current)
+			if len(got) != limit {
+				t.Fatalf("len(assembleContext) = %d, want %d", len(got), limit)
+			}
+			if last := got[len(got)-1]; last.ID != current.ID {
+				t.Errorf("последнее сообщение контекста = %d, want текущее %d", last.ID, current.ID)
+			}
+		})
+	}
+}
@@ -0,0 +1,22 @@
+package bot
+
+import tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+// diceMaxValues - максимальное значение броска по эмодзи Telegram Dice.
+var diceMaxValues = map[string]int{
+	"🎲": 6,
+	"🎯": 6,
+	"🎳": 6,
+	"🏀": 5,
+	"⚽": 5,
+	"🎰": 64,
+}
+
+// isNotableDiceRoll сообщает, выпало ли в броске максимальное значение (шестерка, попадание в центр, джекпот).
+func isNotableDiceRoll(dice *tgbotapi.Dice) bool {
+	if dice == nil {
+		return false
+	}
+	maxValue, ok := diceMaxValues[dice.Emoji]
+	return ok && dice.Value == maxValue
+}
@@ -0,0 +1,133 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/Henry-Case-dev/rofloslav/internal/storage"
+)
+
+// directReplyStateFile - файл в DATA_DIR с метками времени прямых обращений (лимит DIRECT_REPLY_LIMIT_COUNT).
+const directReplyStateFile = "direct_reply_timestamps.json"
+
+// directReplyStateSaveInterval - как часто метки времени сохраняются на диск.
+const directReplyStateSaveInterval = time.Minute
+
+// directReplyState - формат файла: chatID -> userID -> метки времени (ключи строками из-за JSON).
+type directReplyState map[string]map[string][]time.Time
+
+// directReplyStatePath возвращает путь к файлу состояния лимитера.
+func directReplyStatePath() string {
+	return filepath.Join(storage.DataDir(), directReplyStateFile)
+}
+
+// loadDirectReplyState восстанавливает метки времени прямых обращений после перезапуска,
+// отбрасывая вышедшие за окно DIRECT_REPLY_WINDOW.
+func (b *Bot) loadDirectReplyState() {
+	data, err := os.ReadFile(directReplyStatePath())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("[DirectReply WARN] Ошибка чтения состояния лимита обращений: %v", err)
+		}
+		return
+	}
+	var state directReplyState
+	if err := json.Unmarshal(data, &state); err != nil {
+		log.Printf("[DirectReply WARN] Ошибка разбора состояния лимита обращений: %v", err)
+		return
+	}
+
+	windowStart := time.Now().Add(-b.config.DirectReplyWindow)
+	restored := 0
+	b.directReplyMutex.Lock()
+	defer b.directReplyMutex.Unlock()
+	for chatKey, users := range state {
+		chatID, err := strconv.ParseInt(chatKey, 10, 64)
+		if err != nil {
+			continue
+		}
+		for userKey, timestamps := range users {
+			userID, err := strconv.ParseInt(userKey, 10, 64)
+			if err != nil {
+				continue
+			}
+			valid := timestampsAfter(timestamps, windowStart)
+			if len(valid) == 0 {
+				continue
+			}
+			if _, ok := b.directReplyTimestamps[chatID]; !ok {
+				b.directReplyTimestamps[chatID] = make(map[int64][]time.Time)
+			}
+			b.directReplyTimestamps[chatID][userID] = valid
+			restored++
+		}
+	}
+	log.Printf("[DirectReply] Восстановлены лимиты прямых обращений для %d пользователей.", restored)
+}
+
+// saveDirectReplyState сохраняет актуальные метки времени прямых обращений на диск.
+func (b *Bot) saveDirectReplyState() error {
+	windowStart := time.Now().Add(-b.config.DirectReplyWindow)
+	state := make(directReplyState)
+	b.directReplyMutex.Lock()
+	for chatID, users := range b.directReplyTimestamps {
+		for userID, timestamps := range users {
+			valid := timestampsAfter(timestamps, windowStart)
+			if len(valid) == 0 {
+				continue
+			}
+			chatKey := strconv.FormatInt(chatID, 10)
+			if _, ok := state[chatKey]; !ok {
+				state[chatKey] = make(map[string][]time.Time)
+			}
+			state[chatKey][strconv.FormatInt(userID, 10)] = valid
+		}
+	}
+	b.directReplyMutex.Unlock()
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации состояния лимита обращений: %w", err)
+	}
+	// Резервная копия не нужна: состояние лимитера перезаписывается каждую минуту
+	if err := storage.WriteFileAtomic(directReplyStatePath(), data, false); err != nil {
+		return fmt.Errorf("ошибка сохранения состояния лимита обращений: %w", err)
+	}
+	return nil
+}
+
+// directReplyStateSaver периодически сохраняет состояние лимитера и сохраняет его при остановке бота.
+func (b *Bot) directReplyStateSaver() {
+	defer b.shutdownWG.Done()
+	ticker := time.NewTicker(directReplyStateSaveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := b.saveDirectReplyState(); err != nil {
+				log.Printf("[DirectReply WARN] %v", err)
+			}
+		case <-b.stop:
+			if err := b.saveDirectReplyState(); err != nil {
+				log.Printf("[DirectReply WARN] %v", err)
+			}
+			return
+		}
+	}
+}
+
+// timestampsAfter возвращает метки времени позже since.
+func timestampsAfter(timestamps []time.Time, since time.Time) []time.Time {
+	var result []time.Time
+	for _, ts := range timestamps {
+		if ts.After(since) {
+			result = append(result, ts)
+		}
+	}
+	return result
+}
@@ -0,0 +1,80 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// commandManagementCommands - команды управления списком отключенных команд, их отключить нельзя.
+var commandManagementCommands = map[string]bool{"disable_cmd": true, "enable_cmd": true}
+
+// normalizeCommandName приводит аргумент (/Summarize, summarize@bot) к имени команды (summarize).
+func normalizeCommandName(name string) string {
+	name = strings.TrimPrefix(strings.TrimSpace(name), "/")
+	if at := strings.Index(name, "@"); at >= 0 {
+		name = name[:at]
+	}
+	return strings.ToLower(name)
+}
+
+// isCommandDisabled проверяет, отключена ли команда в чате (/disable_cmd).
+func (b *Bot) isCommandDisabled(chatID int64, command string) bool {
+	command = strings.ToLower(command)
+	if commandManagementCommands[command] {
+		return false
+	}
+	for _, disabled := range b.getChatSettingsSnapshot(chatID).DisabledCommands {
+		if disabled == command {
+			return true
+		}
+	}
+	return false
+}
+
+// handleDisableCommandCommand обрабатывает /disable_cmd и /enable_cmd: отключает или включает команду в чате.
+// Без аргумента показывает список отключенных команд. Доступна администраторам чата и бота.
+func (b *Bot) handleDisableCommandCommand(message *tgbotapi.Message, disable bool) {
+	chatID := message.Chat.ID
+
+	command := normalizeCommandName(message.CommandArguments())
+	if command == "" {
+		disabled := b.getChatSettingsSnapshot(chatID).DisabledCommands
+		list := "нет"
+		if len(disabled) > 0 {
+			list = "/" + strings.Join(disabled, ", /")
+		}
+		b.sendReply(chatID, fmt.Sprintf("Отключенные команды: %s\nИспользование: /disable_cmd <команда>, /enable_cmd <команда>", list))
+		return
+	}
+	if commandManagementCommands[command] {
+		b.sendReply(chatID, fmt.Sprintf("Команду /%s отключить нельзя.", command))
+		return
+	}
+
+	b.updateChatSettings(chatID, func(s *ChatSettings) {
+		// Собираем новый срез: старый мог быть скопирован в снимки настроек
+		updated := make([]string, 0, len(s.DisabledCommands)+1)
+		for _, existing := range s.DisabledCommands {
+			if existing != command {
+				updated = append(updated, existing)
+			}
+		}
+		if disable {
+			updated = append(updated, command)
+			sort.Strings(updated)
+		}
+		s.DisabledCommands = updated
+	})
+
+	if disable {
+		log.Printf("[Settings] Чат %d: Администратор %d отключил команду /%s", chatID, message.From.ID, command)
+		b.sendReply(chatID, fmt.Sprintf("Команда /%s отключена в этом чате.", command))
+		return
+	}
+	log.Printf("[Settings] Чат %d: Администратор %d включил команду /%s", chatID, message.From.ID, command)
+	b.sendReply(chatID, fmt.Sprintf("Команда /%s включена в этом чате.", command))
+}
@@ -0,0 +1,141 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Henry-Case-dev/rofloslav/internal/types"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// inlineSearchResultsLimit - сколько найденных сообщений показывать в ответ на inline-запрос.
+const inlineSearchResultsLimit = 10
+
+// inlineSearchMaxChats - в скольких чатах пользователя искать по одному inline-запросу.
+const inlineSearchMaxChats = 5
+
+// chatMembershipTTL - сколько помнить результат проверки участия пользователя в чате.
+const chatMembershipTTL = 10 * time.Minute
+
+// chatMembership - закешированный результат getChatMember.
+type chatMembership struct {
+	member    bool
+	checkedAt time.Time
+}
+
+// handleInlineQuery отвечает на inline-запрос "@бот запрос" сообщениями из долговременной памяти.
+// Inline-запрос не привязан к чату, поэтому поиск идет только по чатам, где спрашивающий состоит.
+func (b *Bot) handleInlineQuery(query *tgbotapi.InlineQuery) {
+	text := strings.TrimSpace(query.Query)
+	var results []interface{}
+	if text != "" && query.From != nil && b.storage.SupportsVectorSearch() {
+		results = b.inlineSearchResults(query.From.ID, text)
+	}
+
+	answer := tgbotapi.InlineConfig{
+		InlineQueryID: query.ID,
+		Results:       results,
+		CacheTime:     30,
+		IsPersonal:    true, // Результаты зависят от чатов пользователя
+	}
+	if _, err := b.api.Request(answer); err != nil {
+		log.Printf("[Inline WARN] Не удалось ответить на inline-запрос пользователя %d: %v", query.From.ID, err)
+	}
+}
+
+// inlineSearchResults ищет сообщения по запросу в чатах пользователя и оформляет их как inline-результаты.
+func (b *Bot) inlineSearchResults(userID int64, text string) []interface{} {
+	chatIDs := b.memberChats(userID, inlineSearchMaxChats)
+	if len(chatIDs) == 0 {
+		return nil
+	}
+	perChat := (inlineSearchResultsLimit + len(chatIDs) - 1) / len(chatIDs)
+
+	var found []types.Message
+	for _, chatID := range chatIDs {
+		messages, err := b.storage.FindRelevantMessages(chatID, text, perChat)
+		if err != nil {
+			log.Printf("[Inline WARN] Чат %d: Ошибка поиска по запросу пользователя %d: %v", chatID, userID, err)
+			continue
+		}
+		found = append(found, messages...)
+	}
+	if len(found) > inlineSearchResultsLimit {
+		found = found[:inlineSearchResultsLimit]
+	}
+
+	loc, err := time.LoadLocation(b.config.TimeZone)
+	if err != nil {
+		loc = time.Local
+	}
+	results := make([]interface{}, 0, len(found))
+	for _, msg := range found {
+		if msg.Text == "" {
+			continue
+		}
+		author := displayName(msg.UserName, msg.FirstName)
+		date := time.Unix(int64(msg.Timestamp), 0).In(loc).Format("02.01.2006 15:04")
+		article := tgbotapi.NewInlineQueryResultArticle(
+			fmt.Sprintf("%d_%d_%d", len(results), msg.ChatID, msg.ID), // Порядковый номер гарантирует уникальность ID
+			fmt.Sprintf("%s: %s", author, truncateString(msg.Text, 60)),
+			fmt.Sprintf("«%s»\n— %s, %s", msg.Text, author, date),
+		)
+		article.Description = date
+		results = append(results, article)
+	}
+	log.Printf("[Inline] Пользователь %d: по запросу найдено %d сообщений в %d чатах.", userID, len(results), len(chatIDs))
+	return results
+}
+
+// memberChats возвращает до limit известных боту чатов, в которых состоит пользователь.
+func (b *Bot) memberChats(userID int64, limit int) []int64 {
+	b.settingsMutex.RLock()
+	known := make([]int64, 0, len(b.chatSettings))
+	for chatID := range b.chatSettings {
+		known = append(known, chatID)
+	}
+	b.settingsMutex.RUnlock()
+	sort.Slice(known, func(i, j int) bool { return known[i] < known[j] })
+
+	var chats []int64
+	for _, chatID := range known {
+		if len(chats) >= limit {
+			break
+		}
+		if b.isChatMember(chatID, userID) {
+			chats = append(chats, chatID)
+		}
+	}
+	return chats
+}
+
+// isChatMember проверяет участие пользователя в чате через getChatMember (с кешем на chatMembershipTTL).
+func (b *Bot) isChatMember(chatID, userID int64) bool {
+	if chatID == userID {
+		return true // Личный чат пользователя с ботом
+	}
+	key := [2]int64{chatID, userID}
+	b.membershipMutex.Lock()
+	cached, ok := b.membershipCache[key]
+	b.membershipMutex.Unlock()
+	if ok && time.Since(cached.checkedAt) < chatMembershipTTL {
+		return cached.member
+	}
+
+	member, err := b.api.GetChatMember(tgbotapi.GetChatMemberConfig{
+		ChatConfigWithUser: tgbotapi.ChatConfigWithUser{ChatID: chatID, UserID: userID},
+	})
+	if err != nil {
+		// Пользователь не найден или бот больше не в чате - считаем, что доступа нет
+		member = tgbotapi.ChatMember{Status: "left"}
+	}
+	isMember := !member.HasLeft() && !member.WasKicked()
+
+	b.membershipMutex.Lock()
+	b.membershipCache[key] = chatMembership{member: isMember, checkedAt: time.Now()}
+	b.membershipMutex.Unlock()
+	return isMember
+}
@@ -0,0 +1,91 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// trackJoins учитывает вступивших участников и определяет наплыв: не меньше JOIN_FLOOD_THRESHOLD
+// вступлений за JOIN_FLOOD_WINDOW. При наплыве оповещает администратора и при JOIN_FLOOD_AUTO_MUTE
+// запрещает вступившим писать на JOIN_FLOOD_MUTE_DURATION. Возвращает true, если в чате наплыв.
+func (b *Bot) trackJoins(message *tgbotapi.Message) bool {
+	if b.config.JoinFloodThreshold <= 0 {
+		return false
+	}
+	chatID := message.Chat.ID
+	now := time.Now()
+
+	var joined []tgbotapi.User
+	for _, member := range message.NewChatMembers {
+		if member.ID != b.botID {
+			joined = append(joined, member)
+		}
+	}
+
+	b.joinMutex.Lock()
+	b.pruneJoinTimes(now)
+	recent := b.joinTimes[chatID]
+	for range joined {
+		recent = append(recent, now)
+	}
+	if len(recent) > 0 {
+		b.joinTimes[chatID] = recent
+	}
+	joins := len(recent)
+	b.joinMutex.Unlock()
+
+	if joins < b.config.JoinFloodThreshold {
+		return false
+	}
+
+	log.Printf("[JoinFlood WARN] Чат %d: %d вступлений за %s (порог %d).", chatID, joins, b.config.JoinFloodWindow, b.config.JoinFloodThreshold)
+	action := "новые участники не ограничены"
+	if b.config.JoinFloodAutoMute {
+		action = fmt.Sprintf("новым участникам запрещено писать на %s", b.config.JoinFloodMuteDuration)
+	}
+	b.notifyAdmin(fmt.Sprintf("join_flood_%d", chatID), "Наплыв вступлений в чате %d (%s): %d за %s, %s.",
+		chatID, message.Chat.Title, joins, b.config.JoinFloodWindow, action)
+
+	if b.config.JoinFloodAutoMute {
+		for _, member := range joined {
+			b.muteNewMember(chatID, member.ID)
+		}
+	}
+	return true
+}
+
+// pruneJoinTimes убирает вступления старше JOIN_FLOOD_WINDOW во всех чатах и удаляет чаты с пустым окном,
+// чтобы записи чатов, где больше никто не вступает, не копились. Вызывается под joinMutex.
+func (b *Bot) pruneJoinTimes(now time.Time) {
+	for chatID, times := range b.joinTimes {
+		recent := times[:0]
+		for _, joinedAt := range times {
+			if now.Sub(joinedAt) < b.config.JoinFloodWindow {
+				recent = append(recent, joinedAt)
+			}
+		}
+		if len(recent) == 0 {
+			delete(b.joinTimes, chatID)
+			continue
+		}
+		b.joinTimes[chatID] = recent
+	}
+}
+
+// muteNewMember запрещает участнику писать в чате на JOIN_FLOOD_MUTE_DURATION.
+func (b *Bot) muteNewMember(chatID, userID int64) {
+	restrict := tgbotapi.RestrictChatMemberConfig{
+		ChatMemberConfig: tgbotapi.ChatMemberConfig{ChatID: chatID, UserID: userID},
+		UntilDate:        time.Now().Add(b.config.JoinFloodMuteDuration).Unix(),
+		Permissions:      &tgbotapi.ChatPermissions{},
+	}
+	if _, err := b.api.Request(restrict); err != nil {
+		// Без прав администратора в чате бот не может ограничивать участников
+		log.Printf("[JoinFlood WARN] Чат %d: Не удалось ограничить участника %d: %v", chatID, userID, err)
+		return
+	}
+	log.Printf("[JoinFlood] Чат %d: Участнику %d запрещено писать на %s.", chatID, userID, b.config.JoinFloodMuteDuration)
+}
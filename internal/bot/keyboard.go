@@ -16,6 +16,7 @@ func getMainKeyboard() tgbotapi.InlineKeyboardMarkup {
 		),
 		tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData("⏸️ Пауза", "stop"),
+			tgbotapi.NewInlineKeyboardButtonData("✖️ Закрыть", "close_menu"),
 		),
 	)
 }
@@ -59,3 +60,59 @@ func getSettingsKeyboard(minMessages, maxMessages, dailyTakeTime, summaryInterva
 		),
 	)
 }
+
+// onOffLabel возвращает подпись состояния переключателя
+func onOffLabel(enabled bool) string {
+	if enabled {
+		return "Вкл"
+	}
+	return "Выкл"
+}
+
+// getChatSettingsKeyboard возвращает клавиатуру с переключателями настроек чата
+func getChatSettingsKeyboard(settings ChatSettings) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("↩️ Ответ реплаем: %s", onOffLabel(settings.UseReplyTo)), "toggle_reply_to"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("🤖 Свои сообщения в контексте: %s", onOffLabel(settings.IncludeOwnMessages)), "toggle_own_messages"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("📜 Цитата дня: %s", onOffLabel(settings.QuoteOfDayEnabled)), "toggle_quote_of_day"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("📈 Недельная статистика: %s", onOffLabel(settings.StatsDigestEnabled)), "toggle_stats_digest"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("🎭 Случайное настроение: %s", onOffLabel(settings.MoodsEnabled)), "toggle_moods"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("🚫 Фильтр слов: %s", onOffLabel(settings.WordFilterEnabled)), "toggle_word_filter"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("👋 Приветствие новичков: %s", onOffLabel(settings.WelcomeEnabled)), "toggle_welcome"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("🛡 Проверка новичков: %s", onOffLabel(settings.JoinCaptchaEnabled)), "toggle_join_captcha"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🗑 Очистить историю чата", "ask_"+confirmActionClearHistory),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🔙 Назад", "back_to_main"),
+			tgbotapi.NewInlineKeyboardButtonData("✖️ Закрыть", "close_menu"),
+		),
+	)
+}
+
+// getConfirmKeyboard возвращает клавиатуру подтверждения действия: "Да" выполняет action,
+// "Нет" возвращает в меню настроек.
+func getConfirmKeyboard(action string) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅ Да", confirmCallbackPrefix+action),
+			tgbotapi.NewInlineKeyboardButtonData("❌ Нет", "settings"),
+		),
+	)
+}
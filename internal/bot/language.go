@@ -0,0 +1,119 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// defaultLanguage - язык чатов без /setlang.
+const defaultLanguage = "ru"
+
+// chatPrompts - набор промптов, используемых ботом в конкретном чате.
+type chatPrompts struct {
+	BaseSystem       string // Промпт для случайных ответов (BASE_SYSTEM_PROMPT)
+	DirectReply      string // Промпт для прямых обращений (DIRECT_REPLY_PROMPT)
+	DirectReplyLimit string // Сообщение о превышении лимита обращений (DIRECT_REPLY_LIMIT_PROMPT)
+	Summary          string // Промпт для саммари (SUMMARY_PROMPT)
+	SummaryPrefix    string // Заголовок саммари, добавляемого к промпту ответа
+}
+
+// localizedPrompts - промпты по умолчанию для каждого языка. Используются, только если
+// соответствующий промпт не задан в конфиге (.env): заданный промпт действует во всех чатах.
+var localizedPrompts = map[string]chatPrompts{
+	"ru": {
+		BaseSystem:       "Ты - участник группового чата.",
+		DirectReply:      "Тебе адресовали сообщение:",
+		DirectReplyLimit: "Вы слишком часто обращаетесь ко мне напрямую. Пожалуйста, подождите немного.",
+		Summary:          "Подведи итог этого диалога кратко:",
+		SummaryPrefix:    "Вот краткое содержание предыдущего диалога (саммари):",
+	},
+	"en": {
+		BaseSystem:       "You are a member of a group chat. Always reply in English.",
+		DirectReply:      "Someone addressed a message to you. Reply in English:",
+		DirectReplyLimit: "You are addressing me too often. Please wait a little.",
+		Summary:          "Briefly summarize this conversation in English:",
+		SummaryPrefix:    "Here is a brief summary of the previous conversation:",
+	},
+	"uk": {
+		BaseSystem:       "Ти - учасник групового чату. Завжди відповідай українською.",
+		DirectReply:      "Тобі адресували повідомлення. Відповідай українською:",
+		DirectReplyLimit: "Ви занадто часто звертаєтеся до мене. Будь ласка, зачекайте трохи.",
+		Summary:          "Коротко підсумуй цей діалог українською:",
+		SummaryPrefix:    "Ось короткий зміст попереднього діалогу:",
+	},
+	"de": {
+		BaseSystem:       "Du bist Teilnehmer eines Gruppenchats. Antworte immer auf Deutsch.",
+		DirectReply:      "Jemand hat dir eine Nachricht geschrieben. Antworte auf Deutsch:",
+		DirectReplyLimit: "Du schreibst mir zu oft. Bitte warte ein wenig.",
+		Summary:          "Fasse dieses Gespräch kurz auf Deutsch zusammen:",
+		SummaryPrefix:    "Hier ist eine kurze Zusammenfassung des bisherigen Gesprächs:",
+	},
+}
+
+// promptsForChat возвращает промпты чата: промпты из конфига, а незаданные - по умолчанию для языка чата.
+func (b *Bot) promptsForChat(chatID int64) chatPrompts {
+	prompts, ok := localizedPrompts[b.getChatSettingsSnapshot(chatID).Language]
+	if !ok {
+		prompts = localizedPrompts[defaultLanguage]
+	}
+	if b.config.BaseSystemPrompt != "" {
+		prompts.BaseSystem = b.config.BaseSystemPrompt
+	}
+	if b.config.DirectReplyPrompt != "" {
+		prompts.DirectReply = b.config.DirectReplyPrompt
+	}
+	if b.config.DirectReplyLimitPrompt != "" {
+		prompts.DirectReplyLimit = b.config.DirectReplyLimitPrompt
+	}
+	if b.config.SummaryPrompt != "" {
+		prompts.Summary = b.config.SummaryPrompt
+	}
+	return prompts
+}
+
+// supportedLanguages возвращает список доступных кодов языков: defaultLanguage, затем остальные по алфавиту.
+func supportedLanguages() []string {
+	languages := []string{defaultLanguage}
+	for code := range localizedPrompts {
+		if code != defaultLanguage {
+			languages = append(languages, code)
+		}
+	}
+	sort.Strings(languages[1:])
+	return languages
+}
+
+// handleSetLangCommand устанавливает язык чата: /setlang en. Без аргумента показывает текущий язык.
+// Доступна администраторам чата и бота.
+func (b *Bot) handleSetLangCommand(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+
+	available := strings.Join(supportedLanguages(), ", ")
+	lang := strings.ToLower(strings.TrimSpace(message.CommandArguments()))
+	if lang == "" {
+		current := b.getChatSettingsSnapshot(chatID).Language
+		if current == "" {
+			current = defaultLanguage
+		}
+		b.sendReply(chatID, fmt.Sprintf("Язык чата: %s. Доступные языки: %s\nИспользование: /setlang <код>", current, available))
+		return
+	}
+	if _, ok := localizedPrompts[lang]; !ok {
+		b.sendReply(chatID, fmt.Sprintf("Язык %s не поддерживается. Доступные языки: %s", lang, available))
+		return
+	}
+
+	b.updateChatSettings(chatID, func(s *ChatSettings) {
+		if lang == defaultLanguage {
+			s.Language = ""
+		} else {
+			s.Language = lang
+		}
+	})
+	log.Printf("[Settings] Чат %d: Администратор %d установил язык %s", chatID, message.From.ID, lang)
+	b.sendReply(chatID, fmt.Sprintf("Язык чата установлен: %s", lang))
+}
@@ -0,0 +1,55 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/Henry-Case-dev/rofloslav/internal/config"
+)
+
+func TestPromptsForChat(t *testing.T) {
+	tests := []struct {
+		name     string
+		language string
+		cfg      config.Config
+		want     chatPrompts
+	}{
+		{
+			name: "язык по умолчанию",
+			want: localizedPrompts["ru"],
+		},
+		{
+			name:     "английский чат",
+			language: "en",
+			want:     localizedPrompts["en"],
+		},
+		{
+			name:     "неизвестный язык",
+			language: "xx",
+			want:     localizedPrompts[defaultLanguage],
+		},
+		{
+			name:     "промпт из конфига",
+			language: "en",
+			cfg:      config.Config{SummaryPrompt: "Свой промпт саммари"},
+			want: chatPrompts{
+				BaseSystem:       localizedPrompts["en"].BaseSystem,
+				DirectReply:      localizedPrompts["en"].DirectReply,
+				DirectReplyLimit: localizedPrompts["en"].DirectReplyLimit,
+				Summary:          "Свой промпт саммари",
+				SummaryPrefix:    localizedPrompts["en"].SummaryPrefix,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			b := &Bot{
+				config:       &cfg,
+				chatSettings: map[int64]*ChatSettings{1: {Language: tt.language}},
+			}
+			if got := b.promptsForChat(1); got != tt.want {
+				t.Errorf("promptsForChat() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
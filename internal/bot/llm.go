@@ -0,0 +1,146 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/Henry-Case-dev/rofloslav/internal/config"
+	"github.com/Henry-Case-dev/rofloslav/internal/gemini"
+	"github.com/google/generative-ai-go/genai"
+)
+
+// errLLMUnavailable возвращается генерацией, если бот запущен без LLM (ALLOW_LLMLESS_START).
+var errLLMUnavailable = errors.New("LLM недоступна: бот запущен без клиента Gemini")
+
+// llmAvailable сообщает, доступна ли генерация ответов.
+func (b *Bot) llmAvailable() bool {
+	return b.gemini != nil
+}
+
+// generateContent - единая точка вызова генерации с историей.
+// Используется модель чата (/setmodel), а если она не задана - GEMINI_MODEL_NAME.
+// Если модель вернула ошибку и задана запасная (GEMINI_FALLBACK_MODEL_NAME),
+// запрос прозрачно повторяется на запасной модели. Ошибки подряд учитываются для оповещения ADMIN_NOTIFY_CHAT_ID.
+func (b *Bot) generateContent(ctx context.Context, chatID int64, systemPrompt string, history []*genai.Content, lastMessage string, settings *config.GenerationSettings) (string, error) {
+	if !b.llmAvailable() {
+		return "", errLLMUnavailable
+	}
+	response, err := b.generateContentWithFallback(ctx, chatID, systemPrompt, history, lastMessage, settings)
+	b.recordLLMResult(chatID, err)
+	return response, err
+}
+
+// generateContentWithFallback вызывает модель чата и при ошибке - запасную модель.
+// Каждый запрос учитывается в дневном лимите LLM_DAILY_CALL_BUDGET.
+func (b *Bot) generateContentWithFallback(ctx context.Context, chatID int64, systemPrompt string, history []*genai.Content, lastMessage string, settings *config.GenerationSettings) (string, error) {
+	if err := b.reserveLLMCall(); err != nil {
+		return "", err
+	}
+	model := b.modelForChat(chatID)
+	response, err := b.gemini.GenerateContentWithModel(ctx, model, systemPrompt, history, lastMessage, settings)
+	b.checkRateLimited(model, err)
+	if err == nil || !b.canUseFallbackModel(ctx, model) {
+		return response, err
+	}
+	if budgetErr := b.reserveLLMCall(); budgetErr != nil {
+		return "", errors.Join(err, budgetErr)
+	}
+
+	fallbackModel := b.config.GeminiFallbackModelName
+	log.Printf("[LLM WARN] Модель %s вернула ошибку (%v). Повторяем запрос на запасной модели %s.", model, err, fallbackModel)
+	fallbackResponse, fallbackErr := b.gemini.GenerateContentWithModel(ctx, fallbackModel, systemPrompt, history, lastMessage, settings)
+	if fallbackErr != nil {
+		log.Printf("[LLM ERROR] Запасная модель %s тоже вернула ошибку: %v", fallbackModel, fallbackErr)
+		return "", errors.Join(err, fallbackErr)
+	}
+	return fallbackResponse, nil
+}
+
+// generateArbitraryContent - единая точка вызова генерации по произвольному промпту (без истории).
+// Использует тот же выбор модели и откат на запасную модель, что и generateContent.
+// При LLM_RESPONSE_CACHE одинаковые запросы в течение LLM_RESPONSE_CACHE_TTL отвечаются из кеша.
+// Ошибки подряд учитываются для оповещения ADMIN_NOTIFY_CHAT_ID вместе с ошибками generateContent.
+func (b *Bot) generateArbitraryContent(ctx context.Context, chatID int64, prompt string, settings *config.ArbitraryGenerationSettings) (string, error) {
+	if !b.llmAvailable() {
+		return "", errLLMUnavailable
+	}
+	model := b.modelForChat(chatID)
+	var cacheKey string
+	if b.responseCache != nil {
+		cacheKey = arbitraryResponseCacheKey(model, prompt, settings)
+		if cached, ok := b.responseCache.get(cacheKey); ok {
+			log.Printf("[LLM] Чат %d: Ответ на произвольный промпт взят из кеша.", chatID)
+			return cached, nil
+		}
+	}
+	response, err := b.generateArbitraryContentWithFallback(ctx, model, prompt, settings)
+	b.recordLLMResult(chatID, err)
+	if b.responseCache != nil && err == nil && response != "" {
+		b.responseCache.put(cacheKey, response)
+	}
+	return response, err
+}
+
+// generateArbitraryContentWithFallback вызывает модель model и при ошибке - запасную модель.
+func (b *Bot) generateArbitraryContentWithFallback(ctx context.Context, model string, prompt string, settings *config.ArbitraryGenerationSettings) (string, error) {
+	if err := b.reserveLLMCall(); err != nil {
+		return "", err
+	}
+	response, err := b.gemini.GenerateArbitraryContentWithModel(ctx, model, prompt, settings)
+	b.checkRateLimited(model, err)
+	if err == nil || !b.canUseFallbackModel(ctx, model) {
+		return response, err
+	}
+	if budgetErr := b.reserveLLMCall(); budgetErr != nil {
+		return "", errors.Join(err, budgetErr)
+	}
+
+	fallbackModel := b.config.GeminiFallbackModelName
+	log.Printf("[LLM WARN] Модель %s вернула ошибку (%v). Повторяем запрос на запасной модели %s.", model, err, fallbackModel)
+	fallbackResponse, fallbackErr := b.gemini.GenerateArbitraryContentWithModel(ctx, fallbackModel, prompt, settings)
+	if fallbackErr != nil {
+		log.Printf("[LLM ERROR] Запасная модель %s тоже вернула ошибку: %v", fallbackModel, fallbackErr)
+		return "", errors.Join(err, fallbackErr)
+	}
+	return fallbackResponse, nil
+}
+
+// checkRateLimited оповещает администратора, если модель отклонила запрос из-за лимита или квоты:
+// такие ошибки не проходят сами по себе, в отличие от разовых сбоев.
+func (b *Bot) checkRateLimited(model string, err error) {
+	if errors.Is(err, gemini.ErrRateLimited) {
+		b.notifyAdmin("llm_quota", "Модель %s отклоняет запросы: превышен лимит или квота API (%v).", model, err)
+	}
+}
+
+// canUseFallbackModel проверяет, имеет ли смысл повторять запрос, упавший на модели model, на запасной модели.
+func (b *Bot) canUseFallbackModel(ctx context.Context, model string) bool {
+	fallbackModel := b.config.GeminiFallbackModelName
+	if fallbackModel == "" || fallbackModel == model {
+		return false
+	}
+	// Если время на запрос уже вышло, повтор бессмыслен
+	return ctx.Err() == nil
+}
+
+// sendFallbackReply отправляет FALLBACK_REPLY_TEXT, если на прямое обращение не удалось получить ответ от LLM,
+// чтобы пользователь видел, что бот жив. В каждом чате не чаще раза в FALLBACK_REPLY_COOLDOWN.
+func (b *Bot) sendFallbackReply(chatID int64, replyToMessageID int) {
+	if b.config.FallbackReplyText == "" {
+		return
+	}
+	b.fallbackReplyMutex.Lock()
+	last, ok := b.fallbackReplyTimes[chatID]
+	now := time.Now()
+	if ok && now.Sub(last) < b.config.FallbackReplyCooldown {
+		b.fallbackReplyMutex.Unlock()
+		log.Printf("[LLM] Чат %d: Запасной ответ уже отправлялся %s назад, пропускаем.", chatID, now.Sub(last).Round(time.Second))
+		return
+	}
+	b.fallbackReplyTimes[chatID] = now
+	b.fallbackReplyMutex.Unlock()
+
+	b.sendReplyToUser(chatID, replyToMessageID, b.config.FallbackReplyText)
+}
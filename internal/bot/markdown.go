@@ -0,0 +1,193 @@
+package bot
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// sanitizeMarkdown приводит текст от LLM к виду, который Telegram примет в режиме ModeMarkdown (legacy).
+// LLM обычно пишет "стандартный" Markdown (**жирный**, __курсив__) и часто оставляет
+// непарные символы разметки, из-за которых Telegram отвечает "can't parse entities".
+// Функция:
+//   - заменяет маркеры списков "* " в начале строки на "• ", чтобы они не считались жирным;
+//   - заменяет ** и __ на * и _ (вне блоков кода и URL ссылок), серии из трех и более маркеров не меняет;
+//   - экранирует непарные *, _ и `, а также '[' без ссылки;
+//   - экранирует '_' внутри слов (snake_case), чтобы они не превращались в курсив;
+//   - не трогает URL ссылок [текст](url);
+//   - не трогает содержимое блоков кода ``` и `.
+func sanitizeMarkdown(text string) string {
+	if text == "" {
+		return text
+	}
+
+	var result strings.Builder
+	rest := text
+	for rest != "" {
+		// Ищем ближайший блок кода (``` или `)
+		idx := strings.Index(rest, "`")
+		if idx == -1 {
+			result.WriteString(sanitizeMarkdownPlain(rest))
+			break
+		}
+		result.WriteString(sanitizeMarkdownPlain(rest[:idx]))
+		rest = rest[idx:]
+
+		fence := "`"
+		if strings.HasPrefix(rest, "```") {
+			fence = "```"
+		}
+		end := strings.Index(rest[len(fence):], fence)
+		if end == -1 {
+			// Непарный блок кода: экранируем открывающий символ и продолжаем как обычный текст
+			result.WriteString(strings.Repeat("\\`", len(fence)))
+			rest = rest[len(fence):]
+			continue
+		}
+		closing := len(fence) + end + len(fence)
+		result.WriteString(rest[:closing])
+		rest = rest[closing:]
+	}
+	return result.String()
+}
+
+// markdownListMarker - маркер списка "* " в начале строки (с отступом). В Markdown Telegram такой '*'
+// открывает жирный текст, и список из нескольких пунктов превращается в чередование жирного и обычного.
+var markdownListMarker = regexp.MustCompile(`(?m)^([ \t]*)\* `)
+
+// sanitizeMarkdownPlain обрабатывает участок текста вне блоков кода.
+// URL ссылок [текст](url) копируется как есть: экранирование в нем ломает ссылку.
+func sanitizeMarkdownPlain(s string) string {
+	if s == "" {
+		return s
+	}
+	s = markdownListMarker.ReplaceAllString(s, "${1}• ")
+	runes := []rune(s)
+	runes, inURL := collapseDoubleMarkers(runes, linkURLMask(runes))
+	escaped := make([]bool, len(runes)) // Позиции, перед которыми нужно добавить '\'
+
+	// '_' внутри слова (между буквами/цифрами) всегда экранируем
+	for i, r := range runes {
+		if r == '_' && !inURL[i] && i > 0 && i < len(runes)-1 && isWordRune(runes[i-1]) && isWordRune(runes[i+1]) {
+			escaped[i] = true
+		}
+	}
+
+	// Непарные маркеры: если количество нечетное, экранируем последний
+	for _, marker := range []rune{'*', '_'} {
+		var positions []int
+		for i, r := range runes {
+			if r == marker && !inURL[i] && !escaped[i] && !isEscapedRune(runes, i) {
+				positions = append(positions, i)
+			}
+		}
+		if len(positions)%2 != 0 {
+			escaped[positions[len(positions)-1]] = true
+		}
+	}
+
+	// '[' без последующего "](...)" экранируем
+	for i, r := range runes {
+		if r == '[' && !inURL[i] && !isEscapedRune(runes, i) {
+			if _, _, ok := linkURLBounds(runes, i); !ok {
+				escaped[i] = true
+			}
+		}
+	}
+
+	var b strings.Builder
+	for i, r := range runes {
+		if escaped[i] {
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// collapseDoubleMarkers заменяет ** и __ на * и _ вне URL ссылок. Серии из трех и более
+// маркеров (***x***) не меняются, чтобы не исказить парную разметку. Возвращает новые
+// символы и соответствующую им маску URL.
+func collapseDoubleMarkers(runes []rune, inURL []bool) ([]rune, []bool) {
+	out := make([]rune, 0, len(runes))
+	outURL := make([]bool, 0, len(runes))
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		run := 1
+		if (r == '*' || r == '_') && !inURL[i] {
+			for i+run < len(runes) && runes[i+run] == r && !inURL[i+run] {
+				run++
+			}
+		}
+		if run == 2 {
+			out = append(out, r)
+			outURL = append(outURL, false)
+		} else {
+			out = append(out, runes[i:i+run]...)
+			outURL = append(outURL, inURL[i:i+run]...)
+		}
+		i += run
+	}
+	return out, outURL
+}
+
+// linkURLMask отмечает символы, входящие в URL ссылок [текст](url).
+func linkURLMask(runes []rune) []bool {
+	inURL := make([]bool, len(runes))
+	for i, r := range runes {
+		if r != '[' || inURL[i] || isEscapedRune(runes, i) {
+			continue
+		}
+		if from, to, ok := linkURLBounds(runes, i); ok {
+			for j := from; j < to; j++ {
+				inURL[j] = true
+			}
+		}
+	}
+	return inURL
+}
+
+// isWordRune сообщает, является ли символ частью слова.
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// isEscapedRune проверяет, экранирован ли символ обратным слешем.
+func isEscapedRune(runes []rune, i int) bool {
+	return i > 0 && runes[i-1] == '\\'
+}
+
+// linkURLBounds проверяет, что после '[' идет корректная ссылка вида [текст](url),
+// и возвращает границы url: runes[from:to].
+func linkURLBounds(runes []rune, start int) (from, to int, ok bool) {
+	closeIdx := -1
+	for i := start + 1; i < len(runes); i++ {
+		if runes[i] == '\n' {
+			return 0, 0, false
+		}
+		if runes[i] == ']' {
+			closeIdx = i
+			break
+		}
+	}
+	if closeIdx == -1 || closeIdx+1 >= len(runes) || runes[closeIdx+1] != '(' {
+		return 0, 0, false
+	}
+	for i := closeIdx + 2; i < len(runes); i++ {
+		if runes[i] == ')' {
+			return closeIdx + 2, i, true
+		}
+		if runes[i] == '\n' || runes[i] == ' ' {
+			return 0, 0, false
+		}
+	}
+	return 0, 0, false
+}
+
+// isMarkdownParseError определяет, что Telegram отклонил сообщение из-за ошибки разметки.
+func isMarkdownParseError(err error) bool {
+	if err == nil {
+		return false
+	}
+	return strings.Contains(strings.ToLower(err.Error()), "can't parse entities")
+}
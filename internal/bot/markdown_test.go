@@ -0,0 +1,29 @@
+package bot
+
+import "testing"
+
+func TestSanitizeMarkdown(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "список со звездочками", text: "* один\n* два\n* три", want: "• один\n• два\n• три"},
+		{name: "список с отступом", text: "Пункты:\n  * один\n  * *два*", want: "Пункты:\n  • один\n  • *два*"},
+		{name: "жирный в начале строки", text: "**важно** и *еще*", want: "*важно* и *еще*"},
+		{name: "непарная звездочка", text: "2 * 3 = 6", want: "2 \\* 3 = 6"},
+		{name: "snake_case", text: "поле user_name", want: "поле user\\_name"},
+		{name: "ссылка с подчеркиванием", text: "[тут](https://example.com/a_b)", want: "[тут](https://example.com/a_b)"},
+		{name: "ссылка с двойными маркерами", text: "*см.* [тут](https://example.com/a__b**c)", want: "*см.* [тут](https://example.com/a__b**c)"},
+		{name: "жирный курсив", text: "***x***", want: "***x***"},
+		{name: "блок кода не меняется", text: "```\n* a_b\n```", want: "```\n* a_b\n```"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sanitizeMarkdown(tt.text); got != tt.want {
+				t.Errorf("sanitizeMarkdown(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
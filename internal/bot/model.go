@@ -0,0 +1,94 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/Henry-Case-dev/rofloslav/internal/config"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// modelResetArgument - аргумент /setmodel, возвращающий чат на модель из конфига.
+const modelResetArgument = "default"
+
+// modelForChat возвращает модель генерации для чата: заданную через /setmodel или GEMINI_MODEL_NAME.
+// Сохраненная модель, которой больше нет в GEMINI_ALLOWED_MODELS, не используется.
+func (b *Bot) modelForChat(chatID int64) string {
+	if model := b.getChatSettingsSnapshot(chatID).Model; model != "" && b.modelAllowed(model) {
+		return model
+	}
+	return b.gemini.ModelName()
+}
+
+// modelAllowed проверяет модель по списку GEMINI_ALLOWED_MODELS (пустой список разрешает любую).
+func (b *Bot) modelAllowed(model string) bool {
+	allowed := b.config.GeminiAllowedModels
+	if len(allowed) == 0 || model == b.gemini.ModelName() {
+		return true
+	}
+	for _, name := range allowed {
+		if name == model {
+			return true
+		}
+	}
+	return false
+}
+
+// handleSetModelCommand меняет модель генерации чата: /setmodel gemini-2.0-flash.
+// /setmodel default возвращает модель из конфига, без аргумента показывает текущую модель.
+// Доступна только администраторам бота.
+func (b *Bot) handleSetModelCommand(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	if !b.llmAvailable() {
+		b.sendReply(chatID, "Бот запущен без LLM, модель изменить нельзя.")
+		return
+	}
+
+	model := strings.TrimSpace(message.CommandArguments())
+	if model == "" {
+		b.sendReply(chatID, fmt.Sprintf("Модель чата: %s\nИспользование: /setmodel <модель> или /setmodel %s", b.modelForChat(chatID), modelResetArgument))
+		return
+	}
+
+	if model == modelResetArgument {
+		b.updateChatSettings(chatID, func(s *ChatSettings) { s.Model = "" })
+		log.Printf("[Settings] Чат %d: Администратор %d вернул модель по умолчанию", chatID, message.From.ID)
+		b.sendReply(chatID, fmt.Sprintf("Модель чата сброшена на модель по умолчанию: %s", b.gemini.ModelName()))
+		return
+	}
+
+	if err := b.validateModel(model); err != nil {
+		log.Printf("[Settings WARN] Чат %d: Модель %s отклонена: %v", chatID, model, err)
+		b.sendReply(chatID, fmt.Sprintf("Модель %s не установлена: %v", model, err))
+		return
+	}
+
+	b.updateChatSettings(chatID, func(s *ChatSettings) { s.Model = model })
+	log.Printf("[Settings] Чат %d: Администратор %d установил модель %s", chatID, message.From.ID, model)
+	b.sendReply(chatID, fmt.Sprintf("Модель чата установлена: %s", model))
+}
+
+// validateModel проверяет модель перед сохранением в настройках чата: по списку GEMINI_ALLOWED_MODELS,
+// а если он пуст - тестовым запросом на один токен. Модель из GEMINI_MODEL_NAME разрешена всегда.
+func (b *Bot) validateModel(model string) error {
+	if model == b.gemini.ModelName() {
+		return nil
+	}
+	if allowed := b.config.GeminiAllowedModels; len(allowed) > 0 {
+		if !b.modelAllowed(model) {
+			return fmt.Errorf("модели нет в списке разрешенных. Доступные модели: %s", strings.Join(allowed, ", "))
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), b.responseTimeout)
+	defer cancel()
+	maxTokens := 1
+	// Вызываем клиент напрямую: откат на запасную модель скрыл бы ошибку проверяемой модели
+	if _, err := b.gemini.GenerateArbitraryContentWithModel(ctx, model, "ping", &config.ArbitraryGenerationSettings{MaxOutputTokens: &maxTokens}); err != nil {
+		return fmt.Errorf("тестовый запрос к модели завершился ошибкой (%v)", err)
+	}
+	return nil
+}
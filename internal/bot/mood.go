@@ -0,0 +1,14 @@
+package bot
+
+import "math/rand"
+
+// withMood добавляет к системному промпту случайное настроение из PERSONA_MOODS,
+// чтобы тон ответов не был однообразным. В чатах с отключенными настроениями промпт не меняется.
+func (b *Bot) withMood(chatID int64, prompt string) string {
+	moods := b.config.PersonaMoods
+	if len(moods) == 0 || !b.getChatSettingsSnapshot(chatID).MoodsEnabled {
+		return prompt
+	}
+	mood := moods[rand.Intn(len(moods))]
+	return "Твое настроение в этом ответе: " + mood + ".\n\n" + prompt
+}
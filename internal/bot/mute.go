@@ -0,0 +1,95 @@
+package bot
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/Henry-Case-dev/rofloslav/internal/storage"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// botStateFile - файл в DATA_DIR с глобальным состоянием бота (пауза ответов /mute_bot).
+const botStateFile = "bot_state.json"
+
+// mutedReplyText - ответ на команды, генерирующие текст через LLM, пока ответы на паузе.
+const mutedReplyText = "Ответы бота приостановлены администратором (/unmute_bot)."
+
+// botState - формат файла глобального состояния бота.
+type botState struct {
+	RepliesMuted bool `json:"replies_muted"`
+}
+
+// botStatePath возвращает путь к файлу глобального состояния бота.
+func botStatePath() string {
+	return filepath.Join(storage.DataDir(), botStateFile)
+}
+
+// repliesEnabled сообщает, может ли бот отвечать: генерировать ответы, саммари и публикации по расписанию.
+// Сохранение сообщений и команды работают и на паузе.
+func (b *Bot) repliesEnabled() bool {
+	return !b.repliesMuted.Load()
+}
+
+// loadBotState восстанавливает паузу ответов после перезапуска.
+func (b *Bot) loadBotState() {
+	var state botState
+	_, err := storage.ReadFileWithBackup(botStatePath(), func(data []byte) error {
+		return json.Unmarshal(data, &state)
+	})
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Printf("[Mute WARN] Ошибка загрузки состояния бота: %v", err)
+		}
+		return
+	}
+	b.repliesMuted.Store(state.RepliesMuted)
+	if state.RepliesMuted {
+		log.Println("[Mute] Ответы бота приостановлены (сохранено командой /mute_bot).")
+	}
+}
+
+// setRepliesMuted включает или выключает паузу ответов и сохраняет ее на диск.
+func (b *Bot) setRepliesMuted(muted bool) error {
+	b.repliesMuted.Store(muted)
+	data, err := json.Marshal(botState{RepliesMuted: muted})
+	if err != nil {
+		return err
+	}
+	return storage.WriteFileAtomic(botStatePath(), data, false)
+}
+
+// handleMuteCommand обрабатывает /mute_bot и /unmute_bot: глобальная пауза всех ответов бота во всех чатах.
+// Доступна только администраторам бота.
+func (b *Bot) handleMuteCommand(message *tgbotapi.Message, muted bool) {
+	chatID := message.Chat.ID
+
+	if err := b.setRepliesMuted(muted); err != nil {
+		log.Printf("[Mute ERROR] Не удалось сохранить состояние паузы: %v", err)
+	}
+	if muted {
+		log.Printf("[Mute] Администратор %d приостановил ответы бота (чат %d).", message.From.ID, chatID)
+		b.sendReply(chatID, "Ответы бота приостановлены во всех чатах. Сообщения продолжают сохраняться. Вернуть: /unmute_bot")
+		return
+	}
+	log.Printf("[Mute] Администратор %d возобновил ответы бота (чат %d).", message.From.ID, chatID)
+	b.sendReply(chatID, "Ответы бота возобновлены.")
+}
+
+// handleChatRepliesCommand обрабатывает /bot_quiet и /bot_talk: включает или выключает ответы бота
+// только в текущем чате. Сохранение сообщений и команды продолжают работать.
+// Доступна администраторам чата и бота.
+func (b *Bot) handleChatRepliesCommand(message *tgbotapi.Message, enabled bool) {
+	chatID := message.Chat.ID
+
+	b.updateChatSettings(chatID, func(s *ChatSettings) { s.RepliesEnabled = enabled })
+	if enabled {
+		log.Printf("[Settings] Чат %d: Администратор %d включил ответы бота", chatID, message.From.ID)
+		b.sendReply(chatID, "Бот снова отвечает в этом чате.")
+		return
+	}
+	log.Printf("[Settings] Чат %d: Администратор %d включил тихий режим", chatID, message.From.ID)
+	b.sendReply(chatID, "Тихий режим: бот не будет отвечать в этом чате, но продолжит сохранять сообщения. Вернуть: /bot_talk")
+}
@@ -0,0 +1,90 @@
+package bot
+
+import (
+	"log"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/Henry-Case-dev/rofloslav/internal/types"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// promptData - переменные, доступные в шаблонах промптов ({{.ChatTitle}}, {{.Date}} и т.д.).
+type promptData struct {
+	ChatTitle    string // Название чата (для личных сообщений - имя собеседника)
+	Date         string // Текущая дата (ДД.ММ.ГГГГ) в часовом поясе TIMEZONE
+	Time         string // Текущее время (ЧЧ:ММ) в часовом поясе TIMEZONE
+	UserName     string // Автор сообщения, на которое отвечает бот (@username или имя)
+	BotName      string // Имя бота
+	Participants string // Участники из контекста через запятую
+}
+
+// newPromptData собирает переменные шаблона для сообщения и контекста.
+func (b *Bot) newPromptData(message *tgbotapi.Message, contextMessages []types.Message) promptData {
+	now := time.Now()
+	if loc, err := time.LoadLocation(b.config.TimeZone); err == nil {
+		now = now.In(loc)
+	}
+	data := promptData{
+		Date:    now.Format("02.01.2006"),
+		Time:    now.Format("15:04"),
+		BotName: b.api.Self.FirstName,
+	}
+	if message != nil {
+		if message.Chat != nil {
+			data.ChatTitle = message.Chat.Title
+			if data.ChatTitle == "" {
+				data.ChatTitle = strings.TrimSpace(message.Chat.FirstName + " " + message.Chat.LastName)
+			}
+		}
+		if message.From != nil {
+			data.UserName = displayName(message.From.UserName, message.From.FirstName)
+		}
+	}
+
+	seen := make(map[string]struct{})
+	var participants []string
+	for _, msg := range contextMessages {
+		if msg.IsBot {
+			continue
+		}
+		name := displayName(msg.UserName, msg.FirstName)
+		if name == "" {
+			continue
+		}
+		if _, ok := seen[name]; !ok {
+			seen[name] = struct{}{}
+			participants = append(participants, name)
+		}
+	}
+	data.Participants = strings.Join(participants, ", ")
+	return data
+}
+
+// renderPrompt подставляет переменные в промпт, заданный как шаблон text/template.
+// Промпты без "{{" возвращаются как есть. При ошибке шаблона используется исходный текст.
+func renderPrompt(prompt string, data promptData) string {
+	if !strings.Contains(prompt, "{{") {
+		return prompt
+	}
+	tmpl, err := template.New("prompt").Option("missingkey=zero").Parse(prompt)
+	if err != nil {
+		log.Printf("[Prompt WARN] Ошибка разбора шаблона промпта, используем промпт без подстановки: %v", err)
+		return prompt
+	}
+	var result strings.Builder
+	if err := tmpl.Execute(&result, data); err != nil {
+		log.Printf("[Prompt WARN] Ошибка подстановки переменных в промпт, используем промпт без подстановки: %v", err)
+		return prompt
+	}
+	return result.String()
+}
+
+// displayName возвращает @username или имя, если username не задан.
+func displayName(userName, firstName string) string {
+	if userName != "" {
+		return "@" + userName
+	}
+	return firstName
+}
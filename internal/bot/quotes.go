@@ -0,0 +1,120 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Henry-Case-dev/rofloslav/internal/types"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// getRandomStoredMessage возвращает случайное сообщение чата из основного хранилища,
+// а если там ничего не нашлось - из локального.
+func (b *Bot) getRandomStoredMessage(chatID int64) (*types.Message, error) {
+	msg, err := b.storage.GetRandomMessage(chatID)
+	if err != nil {
+		log.Printf("[Quote WARN] Чат %d: Ошибка получения случайного сообщения из основного хранилища: %v", chatID, err)
+	}
+	if msg == nil && b.localHistory != b.storage {
+		return b.localHistory.GetRandomMessage(chatID)
+	}
+	return msg, err
+}
+
+// quoteOfDayCandidates - сколько случайных сообщений рассматривается для "цитаты дня".
+// Из них выбирается самое длинное: короткие реплики вроде "ага" редко бывают запоминающимися.
+const quoteOfDayCandidates = 5
+
+// quoteOfDayScheduler раз в день в QUOTE_OF_DAY_TIME (час в TIMEZONE) публикует
+// "цитату дня" во всех активных чатах, где она не отключена в настройках.
+func (b *Bot) quoteOfDayScheduler() {
+	loc, err := time.LoadLocation(b.config.TimeZone)
+	if err != nil {
+		log.Printf("[Quote WARN] Неизвестный часовой пояс '%s', используем UTC: %v", b.config.TimeZone, err)
+		loc = time.UTC
+	}
+	log.Printf("[Quote] Планировщик цитаты дня запущен (время: %02d:00 %s).", b.config.QuoteOfDayTime, loc)
+
+	for {
+		now := time.Now().In(loc)
+		next := time.Date(now.Year(), now.Month(), now.Day(), b.config.QuoteOfDayTime, 0, 0, 0, loc)
+		if !next.After(now) {
+			next = next.AddDate(0, 0, 1)
+		}
+		timer := time.NewTimer(next.Sub(now))
+		select {
+		case <-timer.C:
+			b.postQuotesOfDay()
+		case <-b.stop:
+			timer.Stop()
+			log.Println("[Quote] Планировщик цитаты дня остановлен.")
+			return
+		}
+	}
+}
+
+// postQuotesOfDay публикует цитату дня во всех подходящих чатах.
+func (b *Bot) postQuotesOfDay() {
+	if !b.repliesEnabled() {
+		log.Println("[Quote] Ответы бота приостановлены (/mute_bot), цитаты дня пропущены.")
+		return
+	}
+	b.settingsMutex.RLock()
+	var chatIDs []int64
+	for chatID, settings := range b.chatSettings {
+		if settings.Active && settings.QuoteOfDayEnabled {
+			chatIDs = append(chatIDs, chatID)
+		}
+	}
+	b.settingsMutex.RUnlock()
+
+	for _, chatID := range chatIDs {
+		quote := b.pickQuoteOfDay(chatID)
+		if quote == nil {
+			log.Printf("[Quote] Чат %d: Нет подходящих сообщений для цитаты дня.", chatID)
+			continue
+		}
+		b.sendReply(chatID, "📜 Цитата дня:\n\n"+formatQuote(quote))
+	}
+}
+
+// pickQuoteOfDay выбирает самое длинное сообщение из нескольких случайных.
+func (b *Bot) pickQuoteOfDay(chatID int64) *types.Message {
+	var best *types.Message
+	for i := 0; i < quoteOfDayCandidates; i++ {
+		msg, err := b.getRandomStoredMessage(chatID)
+		if err != nil || msg == nil {
+			continue
+		}
+		if best == nil || len([]rune(msg.Text)) > len([]rune(best.Text)) {
+			best = msg
+		}
+	}
+	return best
+}
+
+// formatQuote оформляет сохраненное сообщение как цитату с автором и датой.
+func formatQuote(msg *types.Message) string {
+	author := displayName(msg.UserName, msg.FirstName)
+	if author == "" {
+		author = fmt.Sprintf("User %d", msg.UserID)
+	}
+	date := time.Unix(int64(msg.Timestamp), 0).Format("02.01.2006")
+	return fmt.Sprintf("«%s»\n\n— %s, %s", messageTextForLLM(*msg), author, date)
+}
+
+// handleRandomCommand обрабатывает /random: репостит случайное сообщение из истории чата.
+func (b *Bot) handleRandomCommand(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	msg, err := b.getRandomStoredMessage(chatID)
+	if err != nil {
+		b.sendReply(chatID, "Не удалось достать случайное сообщение. Попробуйте позже.")
+		return
+	}
+	if msg == nil {
+		b.sendReply(chatID, "В истории этого чата пока нечего вспомнить.")
+		return
+	}
+	b.sendReply(chatID, formatQuote(msg))
+}
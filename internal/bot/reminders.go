@@ -0,0 +1,225 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Henry-Case-dev/rofloslav/internal/storage"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// remindersFile - файл в DATA_DIR с ожидающими напоминаниями (/remind).
+const remindersFile = "reminders.json"
+
+// maxReminderDelay - самое дальнее напоминание, которое можно поставить.
+const maxReminderDelay = 30 * 24 * time.Hour
+
+// maxRemindersPerChat - сколько напоминаний может ожидать в одном чате.
+const maxRemindersPerChat = 50
+
+// reminder - напоминание, которое бот отправит в чат в DueAt.
+type reminder struct {
+	ID       int64     `json:"id"`
+	ChatID   int64     `json:"chat_id"`
+	UserID   int64     `json:"user_id"`
+	UserName string    `json:"user_name"` // Кому напомнить (@username или имя)
+	Text     string    `json:"text"`
+	DueAt    time.Time `json:"due_at"`
+
+	cancel context.CancelFunc // Отменяет таймер (/cancelremind)
+}
+
+// remindersPath возвращает путь к файлу напоминаний.
+func remindersPath() string {
+	return filepath.Join(storage.DataDir(), remindersFile)
+}
+
+// loadReminders восстанавливает напоминания после перезапуска. Просроченные за время простоя
+// отправляются сразу.
+func (b *Bot) loadReminders() {
+	var saved []*reminder
+	_, err := storage.ReadFileWithBackup(remindersPath(), func(data []byte) error {
+		return json.Unmarshal(data, &saved)
+	})
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Printf("[Reminders WARN] Ошибка загрузки напоминаний: %v", err)
+		}
+		return
+	}
+	b.remindersMutex.Lock()
+	for _, r := range saved {
+		if r.ID > b.lastReminderID {
+			b.lastReminderID = r.ID
+		}
+		b.scheduleReminder(r)
+	}
+	b.remindersMutex.Unlock()
+	log.Printf("[Reminders] Восстановлено напоминаний: %d", len(saved))
+}
+
+// saveRemindersLocked сохраняет ожидающие напоминания на диск. Вызывается под remindersMutex.
+func (b *Bot) saveRemindersLocked() {
+	pending := make([]*reminder, 0, len(b.reminders))
+	for _, r := range b.reminders {
+		pending = append(pending, r)
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].ID < pending[j].ID })
+	data, err := json.MarshalIndent(pending, "", "  ")
+	if err == nil {
+		err = storage.WriteFileAtomic(remindersPath(), data, false)
+	}
+	if err != nil {
+		log.Printf("[Reminders ERROR] Не удалось сохранить напоминания: %v", err)
+	}
+}
+
+// scheduleReminder запускает таймер напоминания. Вызывается под remindersMutex.
+func (b *Bot) scheduleReminder(r *reminder) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	b.reminders[r.ID] = r
+	go func() {
+		timer := time.NewTimer(time.Until(r.DueAt))
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return
+		case <-b.stop:
+			return
+		}
+
+		b.remindersMutex.Lock()
+		if _, ok := b.reminders[r.ID]; !ok {
+			b.remindersMutex.Unlock()
+			return
+		}
+		delete(b.reminders, r.ID)
+		b.saveRemindersLocked()
+		b.remindersMutex.Unlock()
+
+		log.Printf("[Reminders] Чат %d: Напоминание %d для пользователя %d.", r.ChatID, r.ID, r.UserID)
+		b.sendReply(r.ChatID, fmt.Sprintf("⏰ %s, напоминание: %s", r.UserName, r.Text))
+	}()
+}
+
+// handleRemindCommand ставит напоминание: /remind 30m купить хлеб.
+func (b *Bot) handleRemindCommand(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	usage := "Использование: /remind <через сколько> <текст>, например: /remind 30m проверить духовку или /remind 2h30m созвон"
+	args := strings.Fields(message.CommandArguments())
+	if len(args) < 2 || message.From == nil {
+		b.sendReply(chatID, usage)
+		return
+	}
+	delay, err := time.ParseDuration(args[0])
+	if err != nil || delay <= 0 {
+		b.sendReply(chatID, fmt.Sprintf("Не удалось разобрать время '%s'. %s", args[0], usage))
+		return
+	}
+	if delay > maxReminderDelay {
+		b.sendReply(chatID, fmt.Sprintf("Напоминание можно поставить не дальше чем на %s.", maxReminderDelay))
+		return
+	}
+	// Текст берем из исходных аргументов, чтобы сохранить переносы строк
+	text := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(message.CommandArguments()), args[0]))
+
+	b.remindersMutex.Lock()
+	pendingInChat := 0
+	for _, r := range b.reminders {
+		if r.ChatID == chatID {
+			pendingInChat++
+		}
+	}
+	if pendingInChat >= maxRemindersPerChat {
+		b.remindersMutex.Unlock()
+		b.sendReply(chatID, fmt.Sprintf("В чате уже %d напоминаний, отмените ненужные: /reminders", pendingInChat))
+		return
+	}
+	b.lastReminderID++
+	r := &reminder{
+		ID:       b.lastReminderID,
+		ChatID:   chatID,
+		UserID:   message.From.ID,
+		UserName: displayName(message.From.UserName, message.From.FirstName),
+		Text:     text,
+		DueAt:    time.Now().Add(delay),
+	}
+	b.scheduleReminder(r)
+	b.saveRemindersLocked()
+	b.remindersMutex.Unlock()
+
+	log.Printf("[Reminders] Чат %d: Пользователь %d поставил напоминание %d через %s.", chatID, r.UserID, r.ID, delay)
+	b.sendReplyToUser(chatID, message.MessageID, fmt.Sprintf("Напомню через %s (№%d). Отменить: /cancelremind %d", delay, r.ID, r.ID))
+}
+
+// handleRemindersCommand показывает ожидающие напоминания чата (/reminders).
+func (b *Bot) handleRemindersCommand(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	b.remindersMutex.Lock()
+	var pending []*reminder
+	for _, r := range b.reminders {
+		if r.ChatID == chatID {
+			pending = append(pending, r)
+		}
+	}
+	b.remindersMutex.Unlock()
+
+	if len(pending) == 0 {
+		b.sendReply(chatID, "Напоминаний нет. Поставить: /remind <через сколько> <текст>")
+		return
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].DueAt.Before(pending[j].DueAt) })
+	var sb strings.Builder
+	sb.WriteString("⏰ Напоминания:\n")
+	for _, r := range pending {
+		left := time.Until(r.DueAt).Round(time.Minute)
+		if left < time.Minute {
+			left = time.Until(r.DueAt).Round(time.Second)
+		}
+		sb.WriteString(fmt.Sprintf("\n№%d через %s для %s: %s", r.ID, left, r.UserName, truncateString(r.Text, 100)))
+	}
+	sb.WriteString("\n\nОтменить: /cancelremind <номер>")
+	b.sendReply(chatID, sb.String())
+}
+
+// handleCancelRemindCommand отменяет напоминание: /cancelremind <номер>.
+// Отменить может автор напоминания или администратор бота.
+func (b *Bot) handleCancelRemindCommand(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	id, err := strconv.ParseInt(strings.TrimPrefix(strings.TrimSpace(message.CommandArguments()), "№"), 10, 64)
+	if err != nil || message.From == nil {
+		b.sendReply(chatID, "Использование: /cancelremind <номер>. Номера напоминаний: /reminders")
+		return
+	}
+
+	b.remindersMutex.Lock()
+	r, ok := b.reminders[id]
+	if !ok || r.ChatID != chatID {
+		b.remindersMutex.Unlock()
+		b.sendReply(chatID, fmt.Sprintf("Напоминание №%d не найдено.", id))
+		return
+	}
+	if r.UserID != message.From.ID && !b.isAdmin(message.From.ID) {
+		b.remindersMutex.Unlock()
+		b.sendReply(chatID, "Отменить напоминание может только его автор или администратор бота.")
+		return
+	}
+	r.cancel()
+	delete(b.reminders, id)
+	b.saveRemindersLocked()
+	b.remindersMutex.Unlock()
+
+	log.Printf("[Reminders] Чат %d: Пользователь %d отменил напоминание %d.", chatID, message.From.ID, id)
+	b.sendReply(chatID, fmt.Sprintf("Напоминание №%d отменено.", id))
+}
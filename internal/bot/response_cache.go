@@ -0,0 +1,92 @@
+package bot
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/Henry-Case-dev/rofloslav/internal/config"
+)
+
+// responseCacheSize - сколько ответов хранит кеш (LLM_RESPONSE_CACHE), самые давние вытесняются.
+const responseCacheSize = 100
+
+// responseCache - небольшой LRU-кеш ответов LLM с временем жизни записей.
+type responseCache struct {
+	mutex   sync.Mutex
+	ttl     time.Duration
+	order   *list.List // Элементы *responseCacheEntry, в начале - недавно использованные
+	entries map[string]*list.Element
+}
+
+// responseCacheEntry - запись кеша ответов.
+type responseCacheEntry struct {
+	key       string
+	response  string
+	expiresAt time.Time
+}
+
+// newResponseCache создает кеш ответов с временем жизни записей ttl.
+func newResponseCache(ttl time.Duration) *responseCache {
+	return &responseCache{
+		ttl:     ttl,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// arbitraryResponseCacheKey возвращает ключ кеша для запроса: хеш модели, промпта и настроек генерации.
+func arbitraryResponseCacheKey(model, prompt string, settings *config.ArbitraryGenerationSettings) string {
+	hash := sha256.New()
+	hash.Write([]byte(model))
+	hash.Write([]byte{0})
+	hash.Write([]byte(prompt))
+	hash.Write([]byte{0})
+	if settings != nil {
+		if encoded, err := json.Marshal(settings); err == nil {
+			hash.Write(encoded)
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// get возвращает сохраненный ответ, если он есть и не устарел.
+func (c *responseCache) get(key string) (string, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	element, ok := c.entries[key]
+	if !ok {
+		return "", false
+	}
+	entry := element.Value.(*responseCacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.order.Remove(element)
+		delete(c.entries, key)
+		return "", false
+	}
+	c.order.MoveToFront(element)
+	return entry.response, true
+}
+
+// put сохраняет ответ, вытесняя самую давнюю запись при переполнении.
+func (c *responseCache) put(key, response string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	expiresAt := time.Now().Add(c.ttl)
+	if element, ok := c.entries[key]; ok {
+		entry := element.Value.(*responseCacheEntry)
+		entry.response = response
+		entry.expiresAt = expiresAt
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&responseCacheEntry{key: key, response: response, expiresAt: expiresAt})
+	if c.order.Len() > responseCacheSize {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*responseCacheEntry).key)
+	}
+}
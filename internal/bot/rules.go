@@ -0,0 +1,50 @@
+package bot
+
+import (
+	"log"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// rulesResetArgument - аргумент /setrules, возвращающий чат к правилам из DEFAULT_RULES.
+const rulesResetArgument = "default"
+
+// rulesForChat возвращает правила чата: заданные через /setrules или DEFAULT_RULES.
+func (b *Bot) rulesForChat(chatID int64) string {
+	if rules := b.getChatSettingsSnapshot(chatID).Rules; rules != "" {
+		return rules
+	}
+	return b.config.DefaultRules
+}
+
+// handleRulesCommand показывает правила чата (/rules).
+func (b *Bot) handleRulesCommand(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	rules := b.rulesForChat(chatID)
+	if rules == "" {
+		b.sendReply(chatID, "Правила для этого чата не заданы.")
+		return
+	}
+	b.sendReply(chatID, "📜 Правила чата:\n\n"+rules)
+}
+
+// handleSetRulesCommand задает правила чата: /setrules <текст> (переносы строк сохраняются).
+// /setrules default возвращает правила из DEFAULT_RULES. Доступна администраторам чата и бота.
+func (b *Bot) handleSetRulesCommand(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+
+	rules := strings.TrimSpace(message.CommandArguments())
+	switch rules {
+	case "":
+		b.sendReply(chatID, "Использование: /setrules <текст правил> или /setrules "+rulesResetArgument)
+	case rulesResetArgument:
+		b.updateChatSettings(chatID, func(s *ChatSettings) { s.Rules = "" })
+		log.Printf("[Settings] Чат %d: Администратор %d сбросил правила чата", chatID, message.From.ID)
+		b.sendReply(chatID, "Правила чата сброшены на правила по умолчанию.")
+	default:
+		b.updateChatSettings(chatID, func(s *ChatSettings) { s.Rules = rules })
+		log.Printf("[Settings] Чат %d: Администратор %d обновил правила чата", chatID, message.From.ID)
+		b.sendReply(chatID, "Правила чата сохранены. Посмотреть: /rules")
+	}
+}
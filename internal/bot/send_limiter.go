@@ -0,0 +1,56 @@
+package bot
+
+import (
+	"sync"
+	"time"
+)
+
+// sendLimiterPruneSize - при каком числе чатов в лимитере удаляются устаревшие записи.
+const sendLimiterPruneSize = 1000
+
+// sendLimiter заранее ограничивает частоту исходящих сообщений под лимиты Telegram:
+// не больше TELEGRAM_SEND_RATE сообщений в секунду суммарно и не чаще TELEGRAM_CHAT_SEND_INTERVAL в один чат.
+// Каждая отправка резервирует ближайший свободный слот, поэтому одновременные отправки
+// (например, планировщики в нескольких чатах) выстраиваются в очередь, а не упираются в 429.
+type sendLimiter struct {
+	mutex          sync.Mutex
+	globalInterval time.Duration
+	chatInterval   time.Duration
+	nextGlobal     time.Time
+	nextChat       map[int64]time.Time
+}
+
+// newSendLimiter создает лимитер на ratePerSecond сообщений в секунду и chatInterval между сообщениями в чат.
+func newSendLimiter(ratePerSecond int, chatInterval time.Duration) *sendLimiter {
+	return &sendLimiter{
+		globalInterval: time.Second / time.Duration(ratePerSecond),
+		chatInterval:   chatInterval,
+		nextChat:       make(map[int64]time.Time),
+	}
+}
+
+// reserve занимает ближайший слот отправки в чат и возвращает, сколько нужно подождать до него.
+func (l *sendLimiter) reserve(chatID int64) time.Duration {
+	now := time.Now()
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	at := now
+	if l.nextGlobal.After(at) {
+		at = l.nextGlobal
+	}
+	if next := l.nextChat[chatID]; next.After(at) {
+		at = next
+	}
+	l.nextGlobal = at.Add(l.globalInterval)
+	l.nextChat[chatID] = at.Add(l.chatInterval)
+
+	if len(l.nextChat) > sendLimiterPruneSize {
+		for id, next := range l.nextChat {
+			if next.Before(now) {
+				delete(l.nextChat, id)
+			}
+		}
+	}
+	return at.Sub(now)
+}
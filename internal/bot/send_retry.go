@@ -0,0 +1,76 @@
+package bot
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// errBotStopping возвращается отправкой, прерванной остановкой бота.
+var errBotStopping = errors.New("бот останавливается, сообщение не отправлено")
+
+// sendMaxRetries - сколько раз повторять отправку после ответа Telegram 429 (Too Many Requests).
+const sendMaxRetries = 3
+
+// sendMaxRetryWait - максимальное ожидание перед повтором; при большем retry_after сообщение не повторяется.
+const sendMaxRetryWait = time.Minute
+
+// send отправляет сообщение в чат chatID. Перед отправкой ждет свободного слота в sendLimiter,
+// а если Telegram все же ответил 429, ждет retry_after и повторяет отправку (до sendMaxRetries раз),
+// чтобы сообщения не терялись при всплесках.
+func (b *Bot) send(chatID int64, c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	for attempt := 0; ; attempt++ {
+		if !b.waitSendSlot(chatID) {
+			return tgbotapi.Message{}, errBotStopping
+		}
+		sent, err := b.api.Send(c)
+		wait, limited := telegramRetryAfter(err)
+		if !limited || attempt >= sendMaxRetries {
+			return sent, err
+		}
+		if wait > sendMaxRetryWait {
+			log.Printf("[WARN] Telegram просит подождать %s перед отправкой, это дольше %s - сообщение не повторяется.", wait, sendMaxRetryWait)
+			return sent, err
+		}
+
+		log.Printf("[WARN] Telegram ограничил частоту отправки (429), повтор через %s (попытка %d/%d).", wait, attempt+1, sendMaxRetries)
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-b.stop:
+			timer.Stop()
+			return sent, err
+		}
+	}
+}
+
+// waitSendSlot ждет слота отправки в чат. Возвращает false, если бот останавливается.
+func (b *Bot) waitSendSlot(chatID int64) bool {
+	wait := b.sendLimiter.reserve(chatID)
+	if wait <= 0 {
+		return true
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-b.stop:
+		return false
+	}
+}
+
+// telegramRetryAfter возвращает retry_after из ошибки Telegram 429 Too Many Requests.
+func telegramRetryAfter(err error) (time.Duration, bool) {
+	var apiErr *tgbotapi.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != 429 {
+		return 0, false
+	}
+	wait := time.Duration(apiErr.RetryAfter) * time.Second
+	if wait <= 0 {
+		wait = time.Second
+	}
+	return wait, true
+}
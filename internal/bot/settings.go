@@ -0,0 +1,197 @@
+package bot
+
+import (
+	"log"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// settingsMenuText - заголовок сообщения с меню настроек
+const settingsMenuText = "⚙️ Настройки чата:"
+
+// mainMenuText - заголовок основного меню (уровень выше настроек)
+const mainMenuText = "🤖 Меню бота:"
+
+// confirmCallbackPrefix - префикс callback подтверждения необратимого действия (confirm_<действие>).
+const confirmCallbackPrefix = "confirm_"
+
+// confirmActionClearHistory - действие "очистить историю чата" (кнопки ask_/confirm_).
+const confirmActionClearHistory = "clear_history"
+
+// adminOnlySettings - кнопки, меняющие настройки чата или отключающие бота. Их могут нажимать
+// только администраторы чата или бота, как и команды /setlang и /setrules; остальные кнопки
+// (меню, саммари) доступны всем.
+var adminOnlySettings = map[string]bool{
+	"toggle_reply_to":     true,
+	"toggle_own_messages": true,
+	"toggle_quote_of_day": true,
+	"toggle_stats_digest": true,
+	"toggle_moods":        true,
+	"toggle_word_filter":  true,
+	"toggle_welcome":      true,
+	"toggle_join_captcha": true,
+	"stop":                true,
+}
+
+// adminOnlySettingText - ответ на нажатие кнопки из adminOnlySettings без прав администратора.
+const adminOnlySettingText = "Эта настройка доступна только администраторам чата и бота."
+
+// sendSettingsMenu отправляет в чат меню настроек с текущими значениями.
+func (b *Bot) sendSettingsMenu(chatID int64) {
+	msg := tgbotapi.NewMessage(chatID, settingsMenuText)
+	msg.ReplyMarkup = getChatSettingsKeyboard(b.getChatSettingsSnapshot(chatID))
+	if _, err := b.send(chatID, msg); err != nil {
+		log.Printf("[ERROR] Чат %d: Не удалось отправить меню настроек: %v", chatID, err)
+	}
+}
+
+// handleCallback обрабатывает нажатия на кнопки inline-клавиатур.
+// На каждый callback отвечаем (при медленной обработке - пустым ответом по таймауту),
+// иначе кнопка у пользователя остается в состоянии загрузки.
+func (b *Bot) handleCallback(callback *tgbotapi.CallbackQuery) {
+	answer := b.newCallbackAnswer(callback)
+	defer answer.answer("")
+	if callback.Message == nil || callback.Message.Chat == nil {
+		return
+	}
+	chatID := callback.Message.Chat.ID
+	log.Printf("Чат %d: Получен callback '%s' от пользователя %d", chatID, callback.Data, callback.From.ID)
+
+	if strings.HasPrefix(callback.Data, captchaCallbackPrefix) {
+		b.handleCaptchaCallback(callback, answer)
+		return
+	}
+
+	if adminOnlySettings[callback.Data] && !b.isChatOrBotAdmin(chatID, callback.From.ID) {
+		log.Printf("[Settings] Чат %d: Пользователь %d без прав администратора нажал '%s', отказано", chatID, callback.From.ID, callback.Data)
+		answer.alert(adminOnlySettingText)
+		return
+	}
+
+	answerText := ""
+	switch callback.Data {
+	case "toggle_reply_to":
+		b.updateChatSettings(chatID, func(s *ChatSettings) { s.UseReplyTo = !s.UseReplyTo })
+		answerText = "Настройка обновлена"
+		b.refreshSettingsMenu(chatID, callback.Message.MessageID)
+	case "toggle_own_messages":
+		b.updateChatSettings(chatID, func(s *ChatSettings) { s.IncludeOwnMessages = !s.IncludeOwnMessages })
+		answerText = "Настройка обновлена"
+		b.refreshSettingsMenu(chatID, callback.Message.MessageID)
+	case "toggle_quote_of_day":
+		b.updateChatSettings(chatID, func(s *ChatSettings) { s.QuoteOfDayEnabled = !s.QuoteOfDayEnabled })
+		answerText = "Настройка обновлена"
+		if !b.config.QuoteOfDayEnabled {
+			answerText = "Настройка сохранена, но цитата дня отключена глобально (QUOTE_OF_DAY_ENABLED)"
+		}
+		b.refreshSettingsMenu(chatID, callback.Message.MessageID)
+	case "toggle_stats_digest":
+		b.updateChatSettings(chatID, func(s *ChatSettings) { s.StatsDigestEnabled = !s.StatsDigestEnabled })
+		answerText = "Настройка обновлена"
+		if !b.config.StatsDigestEnabled {
+			answerText = "Настройка сохранена, но недельная статистика отключена глобально (STATS_DIGEST_ENABLED)"
+		}
+		b.refreshSettingsMenu(chatID, callback.Message.MessageID)
+	case "toggle_moods":
+		b.updateChatSettings(chatID, func(s *ChatSettings) { s.MoodsEnabled = !s.MoodsEnabled })
+		answerText = "Настройка обновлена"
+		if len(b.config.PersonaMoods) == 0 {
+			answerText = "Настройка сохранена, но список настроений не задан (PERSONA_MOODS)"
+		}
+		b.refreshSettingsMenu(chatID, callback.Message.MessageID)
+	case "toggle_word_filter":
+		b.updateChatSettings(chatID, func(s *ChatSettings) { s.WordFilterEnabled = !s.WordFilterEnabled })
+		answerText = "Настройка обновлена"
+		if len(b.config.WordFilter) == 0 {
+			answerText = "Настройка сохранена, но список слов не задан (WORD_FILTER)"
+		}
+		b.refreshSettingsMenu(chatID, callback.Message.MessageID)
+	case "toggle_welcome":
+		b.updateChatSettings(chatID, func(s *ChatSettings) { s.WelcomeEnabled = !s.WelcomeEnabled })
+		answerText = "Настройка обновлена"
+		b.refreshSettingsMenu(chatID, callback.Message.MessageID)
+	case "back_to_main":
+		b.showMenu(chatID, callback.Message.MessageID, mainMenuText, getMainKeyboard())
+	case "settings":
+		b.showMenu(chatID, callback.Message.MessageID, settingsMenuText, getChatSettingsKeyboard(b.getChatSettingsSnapshot(chatID)))
+	case "close_menu":
+		b.deleteMessage(chatID, callback.Message.MessageID)
+	case "summary":
+		if !b.repliesEnabled() {
+			answerText = mutedReplyText
+			break
+		}
+		if b.isCommandDisabled(chatID, "summarize") {
+			answerText = "Команда /summarize отключена в этом чате."
+			break
+		}
+		// Отвечаем до генерации: саммари через LLM дольше таймаута ответа на callback
+		answer.answer("Готовлю саммари...")
+		request := *callback.Message
+		request.From = callback.From
+		b.handleSummarizeCommand(&request)
+	case "stop":
+		b.setChatActive(chatID, false)
+		answerText = "Бот деактивирован для этого чата. Включить: /activate"
+	case "ask_" + confirmActionClearHistory:
+		if !b.isAdmin(callback.From.ID) {
+			answerText = "Очистить историю может только администратор бота."
+			break
+		}
+		b.showMenu(chatID, callback.Message.MessageID, "Точно очистить всю историю чата? Бот забудет все сообщения, отменить это нельзя.", getConfirmKeyboard(confirmActionClearHistory))
+	case confirmCallbackPrefix + confirmActionClearHistory:
+		if !b.isAdmin(callback.From.ID) {
+			answerText = "Очистить историю может только администратор бота."
+			break
+		}
+		b.clearChatHistory(chatID)
+		log.Printf("[Settings] Чат %d: Администратор %d очистил историю чата", chatID, callback.From.ID)
+		answerText = "История чата очищена"
+		b.showMenu(chatID, callback.Message.MessageID, settingsMenuText, getChatSettingsKeyboard(b.getChatSettingsSnapshot(chatID)))
+	case "toggle_join_captcha":
+		b.updateChatSettings(chatID, func(s *ChatSettings) { s.JoinCaptchaEnabled = !s.JoinCaptchaEnabled })
+		answerText = "Настройка обновлена"
+		b.refreshSettingsMenu(chatID, callback.Message.MessageID)
+	default:
+		log.Printf("[WARN] Чат %d: Неизвестный callback '%s'", chatID, callback.Data)
+	}
+
+	answer.answer(answerText)
+}
+
+// clearChatHistory удаляет историю чата из основного и локального хранилищ и сбрасывает кеш саммари.
+func (b *Bot) clearChatHistory(chatID int64) {
+	b.storage.ClearChatHistory(chatID)
+	if b.localHistory != b.storage {
+		b.localHistory.ClearChatHistory(chatID)
+	}
+	b.summaryMutex.Lock()
+	delete(b.lastSummaryRequest, chatID)
+	b.summaryMutex.Unlock()
+}
+
+// showMenu заменяет текст и клавиатуру сообщения с меню (переход между уровнями меню).
+func (b *Bot) showMenu(chatID int64, messageID int, text string, keyboard tgbotapi.InlineKeyboardMarkup) {
+	edit := tgbotapi.NewEditMessageTextAndMarkup(chatID, messageID, text, keyboard)
+	if _, err := b.api.Request(edit); err != nil {
+		log.Printf("[WARN] Чат %d: Не удалось переключить меню: %v", chatID, err)
+	}
+}
+
+// refreshSettingsMenu перерисовывает клавиатуру меню настроек после изменения.
+func (b *Bot) refreshSettingsMenu(chatID int64, messageID int) {
+	edit := tgbotapi.NewEditMessageReplyMarkup(chatID, messageID, getChatSettingsKeyboard(b.getChatSettingsSnapshot(chatID)))
+	if _, err := b.api.Request(edit); err != nil {
+		log.Printf("[WARN] Чат %d: Не удалось обновить меню настроек: %v", chatID, err)
+	}
+}
+
+// updateChatSettings атомарно изменяет настройки чата переданной функцией и планирует их сохранение.
+func (b *Bot) updateChatSettings(chatID int64, update func(s *ChatSettings)) {
+	settings := b.getChatSettings(chatID)
+	b.settingsMutex.Lock()
+	update(settings)
+	b.settingsMutex.Unlock()
+	b.scheduleChatSettingsSave(chatID)
+}
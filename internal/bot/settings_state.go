@@ -0,0 +1,132 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Henry-Case-dev/rofloslav/internal/storage"
+)
+
+// chatSettingsFilePrefix - настройки чата хранятся в DATA_DIR/settings_<chatID>.json.
+const chatSettingsFilePrefix = "settings_"
+
+// chatSettingsSaveDelay - изменения настроек чата за это время (например, несколько нажатий
+// в меню подряд) записываются на диск одним сохранением.
+const chatSettingsSaveDelay = 2 * time.Second
+
+// chatSettingsPath возвращает путь к файлу настроек чата.
+func chatSettingsPath(chatID int64) string {
+	return filepath.Join(storage.DataDir(), fmt.Sprintf("%s%d.json", chatSettingsFilePrefix, chatID))
+}
+
+// loadChatSettings загружает сохраненные настройки всех чатов при старте.
+// Поля, которых нет в файле (добавленные позже настройки), получают значения по умолчанию.
+func (b *Bot) loadChatSettings() {
+	files, err := os.ReadDir(storage.DataDir())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("[Settings WARN] Ошибка чтения директории настроек: %v", err)
+		}
+		return
+	}
+
+	loaded := 0
+	b.settingsMutex.Lock()
+	defer b.settingsMutex.Unlock()
+	chatIDs := make(map[int64]bool)
+	for _, file := range files {
+		// Резервная копия (.bak) без основного файла тоже указывает на сохраненные настройки
+		name := strings.TrimSuffix(file.Name(), ".bak")
+		if file.IsDir() || !strings.HasPrefix(name, chatSettingsFilePrefix) || filepath.Ext(name) != ".json" {
+			continue
+		}
+		chatID, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimPrefix(name, chatSettingsFilePrefix), ".json"), 10, 64)
+		if err != nil {
+			continue
+		}
+		chatIDs[chatID] = true
+	}
+
+	for chatID := range chatIDs {
+		var settings *ChatSettings
+		_, err := storage.ReadFileWithBackup(chatSettingsPath(chatID), func(data []byte) error {
+			settings = b.defaultChatSettings()
+			return json.Unmarshal(data, settings)
+		})
+		if err != nil {
+			log.Printf("[Settings WARN] Чат %d: Ошибка загрузки настроек, используются значения по умолчанию: %v", chatID, err)
+			continue
+		}
+		b.chatSettings[chatID] = settings
+		loaded++
+	}
+	log.Printf("[Settings] Загружены настройки для %d чатов.", loaded)
+}
+
+// scheduleChatSettingsSave откладывает сохранение настроек чата на chatSettingsSaveDelay.
+// Источник истины - настройки в памяти, поэтому все изменения за это время попадут в одну запись.
+// После остановки бота настройки сохраняются сразу: отложенное сохранение уже никто не выполнит.
+func (b *Bot) scheduleChatSettingsSave(chatID int64) {
+	b.settingsSaveMutex.Lock()
+	defer b.settingsSaveMutex.Unlock()
+	select {
+	case <-b.stop:
+		b.saveChatSettings(chatID)
+		return
+	default:
+	}
+	if _, ok := b.settingsSaveTimers[chatID]; ok {
+		return // Сохранение уже запланировано
+	}
+	b.settingsSaveTimers[chatID] = time.AfterFunc(chatSettingsSaveDelay, func() {
+		// Сохраняем под мьютексом, чтобы flushChatSettings дождался начатой записи
+		b.settingsSaveMutex.Lock()
+		defer b.settingsSaveMutex.Unlock()
+		if _, ok := b.settingsSaveTimers[chatID]; !ok {
+			return // Уже сохранено flushChatSettings
+		}
+		delete(b.settingsSaveTimers, chatID)
+		b.saveChatSettings(chatID)
+	})
+}
+
+// flushChatSettings сразу сохраняет настройки всех чатов с отложенным сохранением.
+func (b *Bot) flushChatSettings() {
+	b.settingsSaveMutex.Lock()
+	defer b.settingsSaveMutex.Unlock()
+	flushed := len(b.settingsSaveTimers)
+	for chatID, timer := range b.settingsSaveTimers {
+		timer.Stop()
+		delete(b.settingsSaveTimers, chatID)
+		b.saveChatSettings(chatID)
+	}
+	if flushed > 0 {
+		log.Printf("[Settings] Сохранены отложенные настройки %d чатов.", flushed)
+	}
+}
+
+// chatSettingsSaver сохраняет отложенные настройки при остановке бота.
+func (b *Bot) chatSettingsSaver() {
+	defer b.shutdownWG.Done()
+	<-b.stop
+	b.flushChatSettings()
+}
+
+// saveChatSettings сохраняет настройки чата на диск. Обычно вызывается через scheduleChatSettingsSave.
+func (b *Bot) saveChatSettings(chatID int64) {
+	snapshot := b.getChatSettingsSnapshot(chatID)
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		log.Printf("[Settings ERROR] Чат %d: Ошибка сериализации настроек: %v", chatID, err)
+		return
+	}
+	if err := storage.WriteFileAtomic(chatSettingsPath(chatID), data, b.config.LocalStorageBackup); err != nil {
+		log.Printf("[Settings ERROR] Чат %d: Ошибка сохранения настроек: %v", chatID, err)
+	}
+}
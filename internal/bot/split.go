@@ -0,0 +1,72 @@
+package bot
+
+import (
+	"strings"
+)
+
+// telegramMessageLimit - максимальная длина текста одного сообщения Telegram.
+const telegramMessageLimit = 4096
+
+// splitChunkLimit - длина части при разбиении. Оставляем запас под экранирование Markdown.
+const splitChunkLimit = 3900
+
+// splitMessage разбивает длинный текст на части не длиннее limit символов.
+// Старается резать по границам абзацев, строк, предложений и слов
+// и не разрывать блоки кода ```...```. Если блок кода длиннее limit,
+// он закрывается в конце части и открывается заново в следующей.
+func splitMessage(text string, limit int) []string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return []string{text}
+	}
+
+	var chunks []string
+	carryFence := false // Нужно ли открыть блок кода в начале следующей части
+	for len(runes) > 0 {
+		prefix := ""
+		if carryFence {
+			prefix = "```\n"
+		}
+		available := limit - len([]rune(prefix))
+		if len(runes) <= available {
+			chunks = append(chunks, prefix+string(runes))
+			break
+		}
+
+		window := string(runes[:available])
+		cut := findSplitPoint(window)
+
+		// Если разрез приходится внутрь блока кода, пробуем резать перед его началом
+		head := window[:cut]
+		insideCode := (strings.Count(head, "```")%2 == 1) != carryFence
+		if insideCode {
+			if openIdx := strings.LastIndex(head, "```"); openIdx > 0 {
+				cut = openIdx
+				head = window[:cut]
+				insideCode = (strings.Count(head, "```")%2 == 1) != carryFence
+			}
+		}
+
+		chunk := prefix + strings.TrimRight(head, " \n")
+		if insideCode {
+			chunk += "\n```"
+		}
+		if strings.TrimSpace(chunk) != "" {
+			chunks = append(chunks, chunk)
+		}
+		carryFence = insideCode
+		runes = []rune(strings.TrimLeft(string(runes)[len(head):], " \n"))
+	}
+	return chunks
+}
+
+// findSplitPoint возвращает байтовую позицию в window, по которой лучше всего разрезать текст.
+func findSplitPoint(window string) int {
+	minCut := len(window) / 3 // Не режем слишком близко к началу, чтобы не плодить мелкие части
+	for _, sep := range []string{"\n\n", "\n", ". ", "! ", "? ", " "} {
+		if idx := strings.LastIndex(window, sep); idx > minCut {
+			return idx + len(sep)
+		}
+	}
+	return len(window)
+}
@@ -0,0 +1,142 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Henry-Case-dev/rofloslav/internal/storage"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// statsTopUsers - сколько самых активных пользователей показывается в статистике.
+const statsTopUsers = 5
+
+// statsDigestPeriod - период, за который считается еженедельный дайджест.
+const statsDigestPeriod = 7 * 24 * time.Hour
+
+// getChatStats считает статистику чата в основном хранилище, а при ошибке - в локальном.
+func (b *Bot) getChatStats(chatID int64, since time.Time) (*storage.ChatStats, error) {
+	stats, err := b.storage.GetChatStats(chatID, since)
+	if err != nil && b.localHistory != b.storage {
+		log.Printf("[Stats WARN] Чат %d: Ошибка статистики основного хранилища, используем локальное: %v", chatID, err)
+		return b.localHistory.GetChatStats(chatID, since)
+	}
+	return stats, err
+}
+
+// formatChatStats оформляет статистику чата для отправки в чат.
+func formatChatStats(title string, stats *storage.ChatStats) string {
+	var sb strings.Builder
+	sb.WriteString(title)
+	sb.WriteString("\n\n")
+	if stats.TotalMessages == 0 {
+		sb.WriteString("Сообщений за этот период нет.")
+		return sb.String()
+	}
+
+	sb.WriteString(fmt.Sprintf("Сообщений: %d\nАктивных участников: %d\n", stats.TotalMessages, stats.UniqueUsers))
+	if hour, count := stats.BusiestHour(); hour >= 0 {
+		sb.WriteString(fmt.Sprintf("Самый активный час: %02d:00–%02d:00 (%d сообщ.)\n", hour, (hour+1)%24, count))
+	}
+
+	sb.WriteString("\nСамые активные:\n")
+	for i, user := range stats.TopUsers {
+		if i >= statsTopUsers {
+			break
+		}
+		name := user.Name
+		if name == "" {
+			name = fmt.Sprintf("User %d", user.UserID)
+		}
+		sb.WriteString(fmt.Sprintf("%d. %s — %d\n", i+1, name, user.Count))
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// statsDigestScheduler раз в неделю (STATS_DIGEST_WEEKDAY, STATS_DIGEST_TIME в TIMEZONE)
+// публикует статистику за прошедшую неделю во всех активных чатах, где дайджест не отключен.
+func (b *Bot) statsDigestScheduler() {
+	loc, err := time.LoadLocation(b.config.TimeZone)
+	if err != nil {
+		log.Printf("[Stats WARN] Неизвестный часовой пояс '%s', используем UTC: %v", b.config.TimeZone, err)
+		loc = time.UTC
+	}
+	weekday := time.Weekday(b.config.StatsDigestWeekday)
+	log.Printf("[Stats] Планировщик дайджеста статистики запущен (%s, %02d:00 %s).", weekday, b.config.StatsDigestTime, loc)
+
+	for {
+		now := time.Now().In(loc)
+		next := time.Date(now.Year(), now.Month(), now.Day(), b.config.StatsDigestTime, 0, 0, 0, loc)
+		next = next.AddDate(0, 0, (int(weekday)-int(next.Weekday())+7)%7)
+		if !next.After(now) {
+			next = next.AddDate(0, 0, 7)
+		}
+		timer := time.NewTimer(next.Sub(now))
+		select {
+		case <-timer.C:
+			b.postStatsDigests(loc)
+		case <-b.stop:
+			timer.Stop()
+			log.Println("[Stats] Планировщик дайджеста статистики остановлен.")
+			return
+		}
+	}
+}
+
+// postStatsDigests публикует недельный дайджест во всех подходящих чатах.
+func (b *Bot) postStatsDigests(loc *time.Location) {
+	if !b.repliesEnabled() {
+		log.Println("[Stats] Ответы бота приостановлены (/mute_bot), дайджест пропущен.")
+		return
+	}
+	b.settingsMutex.RLock()
+	var chatIDs []int64
+	for chatID, settings := range b.chatSettings {
+		if settings.Active && settings.StatsDigestEnabled {
+			chatIDs = append(chatIDs, chatID)
+		}
+	}
+	b.settingsMutex.RUnlock()
+
+	since := time.Now().In(loc).Add(-statsDigestPeriod)
+	for _, chatID := range chatIDs {
+		stats, err := b.getChatStats(chatID, since)
+		if err != nil {
+			log.Printf("[Stats ERROR] Чат %d: Ошибка подсчета статистики для дайджеста: %v", chatID, err)
+			continue
+		}
+		if stats.TotalMessages == 0 {
+			log.Printf("[Stats] Чат %d: Нет сообщений за неделю, дайджест пропущен.", chatID)
+			continue
+		}
+		b.sendReply(chatID, formatChatStats("📈 Статистика чата за неделю", stats))
+	}
+}
+
+// handleStatsCommand отвечает статистикой чата за STATS_WINDOW или за период из аргумента: /stats 72h.
+func (b *Bot) handleStatsCommand(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	period := b.config.StatsWindow
+	if args := strings.TrimSpace(message.CommandArguments()); args != "" {
+		parsed, err := time.ParseDuration(args)
+		if err != nil || parsed <= 0 {
+			b.sendReply(chatID, fmt.Sprintf("Не удалось разобрать период '%s'. Примеры: 24h, 72h, 168h", args))
+			return
+		}
+		period = parsed
+	}
+
+	loc, err := time.LoadLocation(b.config.TimeZone)
+	if err != nil {
+		loc = time.UTC
+	}
+	stats, err := b.getChatStats(chatID, time.Now().In(loc).Add(-period))
+	if err != nil {
+		log.Printf("[Stats ERROR] Чат %d: Ошибка подсчета статистики: %v", chatID, err)
+		b.sendReply(chatID, "Не удалось посчитать статистику. Попробуйте позже.")
+		return
+	}
+	b.sendReply(chatID, formatChatStats(fmt.Sprintf("📊 Статистика чата за %s", period), stats))
+}
@@ -0,0 +1,267 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Henry-Case-dev/rofloslav/internal/types"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// summaryState хранит время последнего запроса саммари в чате и кеш последнего результата.
+type summaryState struct {
+	lastRequest time.Time
+	cachedText  string // Последнее сгенерированное саммари (/summarize)
+	watermark   int    // Время (Unix) последнего сообщения, вошедшего в кешированное саммари
+	// Время (Unix) последнего сообщения, вошедшего в автоматическое саммари
+	autoWatermark int
+}
+
+// checkSummaryCooldown проверяет кулдаун команд саммари для чата и отмечает новый запрос.
+// Возвращает false (и при необходимости отвечает пользователю), если кулдаун еще не прошел.
+func (b *Bot) checkSummaryCooldown(chatID int64, command string) bool {
+	b.summaryMutex.Lock()
+	state, ok := b.lastSummaryRequest[chatID]
+	if !ok {
+		state = &summaryState{}
+		b.lastSummaryRequest[chatID] = state
+	}
+	lastReq := state.lastRequest
+	now := time.Now()
+	if !lastReq.IsZero() && now.Sub(lastReq) < b.config.SummaryCooldown {
+		b.summaryMutex.Unlock()
+		log.Printf("Кулдаун команды %s для чата %d", command, chatID)
+		if now.Sub(lastReq) < b.config.SummaryCooldown-time.Second*5 {
+			prefix := b.config.SummaryRateLimitStaticPrefix
+			suffix := b.config.SummaryRateLimitStaticSuffix
+			insult := b.config.SummaryRateLimitInsultPrompt
+			if insult == "" {
+				insult = fmt.Sprintf("Команду %s можно использовать раз в %v. Пожалуйста, подождите.", command, b.config.SummaryCooldown)
+			}
+			b.sendReply(chatID, prefix+insult+suffix)
+		}
+		return false
+	}
+	state.lastRequest = now
+	b.summaryMutex.Unlock()
+	return true
+}
+
+// summaryWatermark возвращает время последнего "содержательного" сообщения.
+// Команды и ранее сохраненные саммари не учитываются, иначе сам запрос /summarize
+// каждый раз сдвигал бы отметку и кеш никогда не срабатывал.
+func summaryWatermark(messages []types.Message) int {
+	watermark := 0
+	for _, msg := range messages {
+		if msg.ID == 0 || strings.HasPrefix(msg.Text, "/") {
+			continue
+		}
+		if msg.Timestamp > watermark {
+			watermark = msg.Timestamp
+		}
+	}
+	return watermark
+}
+
+// getCachedSummary возвращает кешированное саммари, если после него не было новых сообщений.
+func (b *Bot) getCachedSummary(chatID int64, watermark int) (string, bool) {
+	b.summaryMutex.Lock()
+	defer b.summaryMutex.Unlock()
+	state, ok := b.lastSummaryRequest[chatID]
+	if !ok || state.cachedText == "" || state.watermark != watermark {
+		return "", false
+	}
+	return state.cachedText, true
+}
+
+// cacheSummary запоминает саммари и отметку последнего вошедшего в него сообщения.
+func (b *Bot) cacheSummary(chatID int64, watermark int, text string) {
+	b.summaryMutex.Lock()
+	defer b.summaryMutex.Unlock()
+	state, ok := b.lastSummaryRequest[chatID]
+	if !ok {
+		state = &summaryState{}
+		b.lastSummaryRequest[chatID] = state
+	}
+	state.cachedText = text
+	state.watermark = watermark
+}
+
+// generateSummary генерирует саммари по сообщениям с учетом лимита MaxMessagesForSummary.
+// message - команда, запросившая саммари (nil для автоматического саммари).
+func (b *Bot) generateSummary(chatID int64, message *tgbotapi.Message, messages []types.Message) (string, error) {
+	// Сортируем по времени и применяем лимит MaxMessagesForSummary
+	contextMessages := append([]types.Message(nil), messages...)
+	sortByTimestamp(contextMessages)
+	if len(contextMessages) > b.config.MaxMessagesForSummary {
+		contextMessages = contextMessages[len(contextMessages)-b.config.MaxMessagesForSummary:]
+		log.Printf("Сообщения для саммаризации в чате %d обрезаны до %d", chatID, b.config.MaxMessagesForSummary)
+	}
+
+	log.Printf("Саммаризация %d сообщений для чата %d...", len(contextMessages), chatID)
+
+	// Отправляем запрос в Gemini для саммаризации
+	geminiHistory := convertMessagesToGenaiContent(contextMessages)
+	lastMessageText := ""
+	prompt := renderPrompt(b.promptsForChat(chatID).Summary, b.newPromptData(message, contextMessages))
+	stopTyping := b.startTyping(chatID)
+	defer stopTyping()
+	ctxSummary, cancelSummary := context.WithTimeout(context.Background(), b.responseTimeout)
+	defer cancelSummary()
+	return b.generateContent(ctxSummary, chatID, prompt, geminiHistory, lastMessageText, b.config.DefaultGenerationSettings)
+}
+
+// storeSummaryMessage сохраняет саммари в локальное хранилище как служебное сообщение (ID 0, роль summary),
+// чтобы оно попадало в контекст последующих ответов.
+func (b *Bot) storeSummaryMessage(chatID int64, text string) {
+	summaryTgMessage := &tgbotapi.Message{
+		MessageID: 0,
+		Chat:      &tgbotapi.Chat{ID: chatID},
+		From: &tgbotapi.User{
+			ID:        b.botID,
+			IsBot:     true,
+			FirstName: b.api.Self.FirstName,
+			UserName:  b.api.Self.UserName,
+		},
+		Date: int(time.Now().Unix()),
+		Text: text,
+	}
+	b.localHistory.AddMessage(chatID, summaryTgMessage)
+	log.Printf("Саммари для чата %d сохранено в локальное хранилище.", chatID)
+}
+
+// autoSummarizeScheduler каждые SUMMARY_INTERVAL_HOURS публикует саммари в активных чатах.
+func (b *Bot) autoSummarizeScheduler() {
+	interval := time.Duration(b.config.SummaryIntervalHours) * time.Hour
+	log.Printf("[AutoSummary] Планировщик авто-саммари запущен (интервал: %s, минимум новых сообщений: %d).", interval, b.config.SummaryMinNewMessages)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			b.postAutoSummaries()
+		case <-b.stop:
+			log.Println("[AutoSummary] Планировщик авто-саммари остановлен.")
+			return
+		}
+	}
+}
+
+// postAutoSummaries генерирует саммари для активных чатов, в которых с прошлого
+// авто-саммари набралось не меньше SUMMARY_MIN_NEW_MESSAGES новых сообщений.
+func (b *Bot) postAutoSummaries() {
+	if !b.repliesEnabled() {
+		log.Println("[Summary] Ответы бота приостановлены (/mute_bot), авто-саммари пропущены.")
+		return
+	}
+	b.settingsMutex.RLock()
+	var chatIDs []int64
+	for chatID, settings := range b.chatSettings {
+		if settings.Active {
+			chatIDs = append(chatIDs, chatID)
+		}
+	}
+	b.settingsMutex.RUnlock()
+
+	for _, chatID := range chatIDs {
+		messages := convertTgMessagesToTypesMessages(b.localHistory.GetMessages(chatID))
+
+		b.summaryMutex.Lock()
+		lastWatermark := 0
+		if state, ok := b.lastSummaryRequest[chatID]; ok {
+			lastWatermark = state.autoWatermark
+		}
+		b.summaryMutex.Unlock()
+
+		newMessages := countNewMessages(messages, lastWatermark)
+		if newMessages == 0 || newMessages < b.config.SummaryMinNewMessages {
+			log.Printf("[AutoSummary] Чат %d: Новых сообщений %d (минимум %d), авто-саммари пропущено.", chatID, newMessages, b.config.SummaryMinNewMessages)
+			continue
+		}
+
+		response, err := b.generateSummary(chatID, nil, messages)
+		if err != nil {
+			log.Printf("[AutoSummary ERROR] Чат %d: Ошибка генерации авто-саммари: %v", chatID, err)
+			continue
+		}
+
+		watermark := summaryWatermark(messages)
+		b.summaryMutex.Lock()
+		state, ok := b.lastSummaryRequest[chatID]
+		if !ok {
+			state = &summaryState{}
+			b.lastSummaryRequest[chatID] = state
+		}
+		state.autoWatermark = watermark
+		b.summaryMutex.Unlock()
+		b.cacheSummary(chatID, watermark, response)
+
+		b.storeSummaryMessage(chatID, response)
+		b.sendReply(chatID, "📋 Саммари:\n\n"+response)
+	}
+}
+
+// countNewMessages считает содержательные сообщения (без команд, служебных и сообщений ботов)
+// новее отметки watermark.
+func countNewMessages(messages []types.Message, watermark int) int {
+	count := 0
+	for _, msg := range messages {
+		if msg.ID == 0 || msg.IsBot || strings.HasPrefix(msg.Text, "/") {
+			continue
+		}
+		if msg.Timestamp > watermark {
+			count++
+		}
+	}
+	return count
+}
+
+// handleSummarySinceCommand обрабатывает команду /summary_since <длительность>, например /summary_since 3h.
+func (b *Bot) handleSummarySinceCommand(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	args := strings.TrimSpace(message.CommandArguments())
+	if args == "" {
+		b.sendReply(chatID, "Укажите период после команды, например: /summary_since 3h или /summary_since 30m")
+		return
+	}
+	period, err := time.ParseDuration(args)
+	if err != nil || period <= 0 {
+		b.sendReply(chatID, fmt.Sprintf("Не удалось разобрать период '%s'. Примеры: 3h, 30m, 1h30m", args))
+		return
+	}
+	log.Printf("Получена команда /summary_since %s в чате %d от пользователя %d", period, chatID, message.From.ID)
+
+	if !b.checkSummaryCooldown(chatID, "/summary_since") {
+		return
+	}
+
+	since := time.Now().Add(-period)
+	rawMessages := b.storage.GetMessagesSince(chatID, since)
+	if len(rawMessages) == 0 && b.localHistory != b.storage {
+		// Основное хранилище (например, Qdrant) может не поддерживать выборку по времени
+		rawMessages = b.localHistory.GetMessagesSince(chatID, since)
+	}
+
+	// Исключаем сохраненные ранее саммари (служебные сообщения без ID)
+	var messages []types.Message
+	for _, msg := range convertTgMessagesToTypesMessages(rawMessages) {
+		if msg.ID != 0 {
+			messages = append(messages, msg)
+		}
+	}
+	if len(messages) == 0 {
+		b.sendReply(chatID, fmt.Sprintf("За последние %s сообщений не найдено.", period))
+		return
+	}
+
+	response, err := b.generateSummary(chatID, message, messages)
+	if err != nil {
+		log.Printf("[Summary ERROR] Чат %d: Ошибка генерации саммари за %s: %v", chatID, period, err)
+		b.sendReply(chatID, "Не удалось сгенерировать саммари. Попробуйте позже.")
+		return
+	}
+	b.sendReply(chatID, fmt.Sprintf("Саммари за последние %s:\n\n%s", period, response))
+}
@@ -0,0 +1,131 @@
+package bot
+
+import (
+	"log"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// adaptiveTriggerWindow - за какой период считается темп сообщений в чате для ADAPTIVE_TRIGGER.
+const adaptiveTriggerWindow = 10 * time.Minute
+
+// replyChance возвращает вероятность случайного ответа для чата.
+// В режиме ADAPTIVE_TRIGGER REPLY_CHANCE масштабируется обратно пропорционально темпу чата:
+// при темпе ADAPTIVE_TRIGGER_BASE_RATE сообщений в минуту используется REPLY_CHANCE как есть,
+// в быстром чате вероятность падает, в медленном растет. Так бот отвечает примерно
+// с одинаковой частотой по времени, а не по числу сообщений.
+func (b *Bot) replyChance(chatID int64) float32 {
+	chance := b.config.ReplyChance
+	if !b.config.AdaptiveTrigger || b.config.AdaptiveTriggerBaseRate <= 0 {
+		return chance
+	}
+
+	rate := b.messageRate(chatID)
+	if rate <= 0 {
+		return chance
+	}
+	adaptive := chance * b.config.AdaptiveTriggerBaseRate / rate
+	if adaptive > 1 {
+		adaptive = 1
+	}
+	if b.config.Debug {
+		log.Printf("[DEBUG] Чат %d: Темп %.2f сообщ./мин, вероятность ответа %.3f (базовая %.3f)", chatID, rate, adaptive, chance)
+	}
+	return adaptive
+}
+
+// messageRate считает темп чата (сообщений в минуту) по меткам времени сообщений
+// из локальной истории и медиа без подписи (mediaTimes) за последние adaptiveTriggerWindow.
+func (b *Bot) messageRate(chatID int64) float32 {
+	now := time.Now()
+	count := b.recentMediaCount(chatID, now)
+	if b.localHistory != nil {
+		since := now.Add(-adaptiveTriggerWindow).Unix()
+		for _, msg := range b.localHistory.GetMessages(chatID) {
+			if msg.From != nil && msg.From.ID == b.botID || !b.countsForTrigger(msg) {
+				continue
+			}
+			if int64(msg.Date) >= since {
+				count++
+			}
+		}
+	}
+	return float32(count) / float32(adaptiveTriggerWindow.Minutes())
+}
+
+// countsForTrigger проверяет, учитывается ли сообщение для случайного ответа и темпа чата.
+// Сообщения без текста и подписи (стикеры, фото, голосовые) учитываются только при TRIGGER_COUNT_INCLUDES_MEDIA.
+func (b *Bot) countsForTrigger(message *tgbotapi.Message) bool {
+	return b.config.TriggerCountIncludesMedia || message.Text != "" || message.Caption != ""
+}
+
+// isMediaOnly проверяет, что сообщение - стикер, фото, голосовое, GIF или видео без подписи.
+// У таких сообщений нет текста, поэтому в историю они не попадают.
+func isMediaOnly(message *tgbotapi.Message) bool {
+	if message.Text != "" || message.Caption != "" {
+		return false
+	}
+	return message.Sticker != nil || len(message.Photo) > 0 || message.Voice != nil ||
+		message.Animation != nil || message.Video != nil
+}
+
+// handleMediaMessage учитывает медиа без подписи в темпе чата и может вызвать случайный ответ.
+// При TRIGGER_COUNT_INCLUDES_MEDIA=false такие сообщения пропускаются.
+func (b *Bot) handleMediaMessage(message *tgbotapi.Message) {
+	if message.From == nil || message.From.ID == b.botID || b.isIgnoredSender(message.From) {
+		return
+	}
+	if !b.trackMediaMessage(message) {
+		return
+	}
+	chatID := message.Chat.ID
+	settings := b.getChatSettingsSnapshot(chatID)
+	if !b.llmAvailable() || !b.repliesEnabled() || !settings.RepliesEnabled || !settings.Active {
+		return
+	}
+	b.ensureHistoryLoaded(chatID)
+	if shouldReply(message, b.config, b.replyChance(chatID)) {
+		b.sendAIResponse(message)
+	}
+}
+
+// trackMediaMessage запоминает время медиа без подписи для messageRate.
+// Возвращает false, если сообщение не учитывается (countsForTrigger).
+func (b *Bot) trackMediaMessage(message *tgbotapi.Message) bool {
+	if !b.countsForTrigger(message) {
+		return false
+	}
+	now := time.Now()
+	b.mediaMutex.Lock()
+	defer b.mediaMutex.Unlock()
+	b.pruneMediaTimes(now)
+	b.mediaTimes[message.Chat.ID] = append(b.mediaTimes[message.Chat.ID], now)
+	return true
+}
+
+// recentMediaCount возвращает число медиа без подписи в чате за последние adaptiveTriggerWindow.
+func (b *Bot) recentMediaCount(chatID int64, now time.Time) int {
+	b.mediaMutex.Lock()
+	defer b.mediaMutex.Unlock()
+	b.pruneMediaTimes(now)
+	return len(b.mediaTimes[chatID])
+}
+
+// pruneMediaTimes убирает медиа старше adaptiveTriggerWindow во всех чатах и удаляет чаты с пустым окном.
+// Вызывается под mediaMutex.
+func (b *Bot) pruneMediaTimes(now time.Time) {
+	for chatID, times := range b.mediaTimes {
+		recent := times[:0]
+		for _, sentAt := range times {
+			if now.Sub(sentAt) < adaptiveTriggerWindow {
+				recent = append(recent, sentAt)
+			}
+		}
+		if len(recent) == 0 {
+			delete(b.mediaTimes, chatID)
+			continue
+		}
+		b.mediaTimes[chatID] = recent
+	}
+}
@@ -0,0 +1,44 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Henry-Case-dev/rofloslav/internal/config"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestTrackMediaMessage(t *testing.T) {
+	sticker := &tgbotapi.Message{
+		MessageID: 1,
+		Chat:      &tgbotapi.Chat{ID: 1},
+		From:      &tgbotapi.User{ID: 2},
+		Sticker:   &tgbotapi.Sticker{FileID: "sticker"},
+	}
+	tests := []struct {
+		name          string
+		includesMedia bool
+		wantCounted   bool
+		wantRate      float32
+	}{
+		{name: "медиа учитываются", includesMedia: true, wantCounted: true, wantRate: 1 / float32(adaptiveTriggerWindow.Minutes())},
+		{name: "медиа не учитываются", includesMedia: false, wantCounted: false, wantRate: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &Bot{
+				config:     &config.Config{TriggerCountIncludesMedia: tt.includesMedia},
+				mediaTimes: make(map[int64][]time.Time),
+			}
+			if !isMediaOnly(sticker) {
+				t.Fatal("isMediaOnly(стикер без подписи) = false, want true")
+			}
+			if got := b.trackMediaMessage(sticker); got != tt.wantCounted {
+				t.Errorf("trackMediaMessage() = %v, want %v", got, tt.wantCounted)
+			}
+			if got := b.messageRate(1); got != tt.wantRate {
+				t.Errorf("messageRate() = %v, want %v", got, tt.wantRate)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package bot
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// handleNewChatMembers приветствует вступивших в чат участников, если приветствие включено в чате.
+// Всех вступивших одним обновлением приветствует одно сообщение, а при наплыве (рейде) приветствия
+// отправляются не чаще раза в WELCOME_COOLDOWN на чат.
+func (b *Bot) handleNewChatMembers(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	settings := b.getChatSettingsSnapshot(chatID)
+	if !settings.WelcomeEnabled || !settings.RepliesEnabled || !b.repliesEnabled() {
+		return
+	}
+
+	var names []string
+	for _, member := range message.NewChatMembers {
+		if member.IsBot {
+			continue
+		}
+		names = append(names, displayName(member.UserName, member.FirstName))
+	}
+	if len(names) == 0 {
+		return
+	}
+
+	b.welcomeMutex.Lock()
+	last, ok := b.welcomeTimes[chatID]
+	now := time.Now()
+	if ok && now.Sub(last) < b.config.WelcomeCooldown {
+		b.welcomeMutex.Unlock()
+		log.Printf("[Welcome] Чат %d: Приветствие отправлялось %s назад, вступление %s пропущено.", chatID, now.Sub(last).Round(time.Second), strings.Join(names, ", "))
+		return
+	}
+	b.welcomeTimes[chatID] = now
+	b.welcomeMutex.Unlock()
+
+	data := b.newPromptData(message, nil)
+	data.UserName = strings.Join(names, ", ")
+	text := renderPrompt(b.config.WelcomeMessage, data)
+	if b.config.WelcomePrompt != "" && b.llmAvailable() {
+		ctx, cancel := context.WithTimeout(context.Background(), b.responseTimeout)
+		defer cancel()
+		generated, err := b.generateArbitraryContent(ctx, chatID, renderPrompt(b.config.WelcomePrompt, data), b.config.DefaultArbitraryGenerationSettings)
+		if err != nil || strings.TrimSpace(generated) == "" {
+			log.Printf("[Welcome WARN] Чат %d: Не удалось сгенерировать приветствие, используется шаблон: %v", chatID, err)
+		} else {
+			text = generated
+		}
+	}
+
+	log.Printf("[Welcome] Чат %d: Приветствие для %s", chatID, data.UserName)
+	b.sendReplyToUser(chatID, message.MessageID, text)
+}
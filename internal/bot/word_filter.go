@@ -0,0 +1,63 @@
+package bot
+
+import (
+	"log"
+	"strings"
+	"unicode"
+
+	"github.com/Henry-Case-dev/rofloslav/internal/storage"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// matchKeywords ищет в тексте ключевые слова (без учета регистра, только целые слова
+// или фразы из целых слов). Возвращает первое найденное слово или пустую строку.
+func matchKeywords(text string, keywords []string) string {
+	normalized := normalizeWords(text)
+	if normalized == "" {
+		return ""
+	}
+	// Пробелы по краям позволяют искать фразы только по границам слов
+	normalized = " " + normalized + " "
+	for _, keyword := range keywords {
+		if phrase := normalizeWords(keyword); phrase != "" && strings.Contains(normalized, " "+phrase+" ") {
+			return keyword
+		}
+	}
+	return ""
+}
+
+// normalizeWords приводит текст к нижнему регистру и оставляет только слова, разделенные одним пробелом.
+func normalizeWords(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, " ")
+}
+
+// applyWordFilter удаляет сообщение со словом из WORD_FILTER, если фильтр включен в чате,
+// и при заданном WORD_FILTER_WARNING предупреждает автора. Сообщения администраторов бота не проверяются.
+// Возвращает true, если сообщение удалено и обрабатывать его дальше не нужно.
+func (b *Bot) applyWordFilter(message *tgbotapi.Message) bool {
+	if len(b.config.WordFilter) == 0 || message.From == nil || b.isAdmin(message.From.ID) {
+		return false
+	}
+	chatID := message.Chat.ID
+	if !b.getChatSettingsSnapshot(chatID).WordFilterEnabled {
+		return false
+	}
+	keyword := matchKeywords(storage.MessageText(message), b.config.WordFilter)
+	if keyword == "" {
+		return false
+	}
+
+	if _, err := b.api.Request(tgbotapi.NewDeleteMessage(chatID, message.MessageID)); err != nil {
+		// Без прав администратора в чате бот не может удалять сообщения
+		log.Printf("[WordFilter WARN] Чат %d: Не удалось удалить сообщение %d со словом '%s': %v", chatID, message.MessageID, keyword, err)
+		return false
+	}
+	log.Printf("[WordFilter] Чат %d: Удалено сообщение %d пользователя %d (слово '%s')", chatID, message.MessageID, message.From.ID, keyword)
+	if warning := b.config.WordFilterWarning; warning != "" {
+		b.sendReply(chatID, displayName(message.From.UserName, message.From.FirstName)+", "+warning)
+	}
+	return true
+}
@@ -53,10 +53,29 @@ func intPtr(v int) *int {
 type Config struct {
 	TelegramToken string  `env:"TELEGRAM_BOT_TOKEN,required"`
 	AdminUserIDs  []int64 // Список ID администраторов
+	// Чат для оповещений о критических ошибках (0 - отключено) и минимальный интервал между одинаковыми оповещениями
+	AdminNotifyChatID   int64         `env:"ADMIN_NOTIFY_CHAT_ID"`
+	AdminNotifyCooldown time.Duration `env:"ADMIN_NOTIFY_COOLDOWN,default=30m"`
+	// Пользователи, сообщения которых бот не сохраняет и на которые не отвечает (например, другие боты)
+	IgnoredUserIDs  []int64
+	IgnoreOtherBots bool `env:"IGNORE_OTHER_BOTS,default=true"` // Игнорировать сообщения от любых ботов (From.IsBot)
+	// Дополнительный префикс команд помимо "/" (например, "!"); пусто - только "/"
+	CommandPrefix string `env:"COMMAND_PREFIX"`
+	// Молча пропускать команды, адресованные другим ботам (/cmd@OtherBot), и неизвестные команды в группах
+	IgnoreForeignCommands bool `env:"IGNORE_FOREIGN_COMMANDS,default=true"`
 	// --- Gemini Settings ---
 	GeminiAPIKey             string `env:"GEMINI_API_KEY,required"`
 	GeminiModelName          string `env:"GEMINI_MODEL_NAME,required"`
 	GeminiEmbeddingModelName string `env:"GEMINI_EMBEDDING_MODEL_NAME,required"`
+	GeminiFallbackModelName  string `env:"GEMINI_FALLBACK_MODEL_NAME"`       // Запасная модель, если основная вернула ошибку (пусто - отключено)
+	GeminiSafetyBlockRetry   bool   `env:"SAFETY_BLOCK_RETRY,default=false"` // Повторять заблокированный фильтром ответ с порогом BLOCK_NONE
+	// Модели, которые можно выбрать через /setmodel (через запятую). Пусто - модель проверяется тестовым запросом
+	GeminiAllowedModels []string `env:"GEMINI_ALLOWED_MODELS"`
+
+	// --- Embedding Settings ---
+	EmbeddingProvider         string        `env:"EMBEDDING_PROVIDER,default=gemini"`     // Провайдер эмбеддингов для долговременной памяти
+	EmbeddingBreakerThreshold int           `env:"EMBEDDING_BREAKER_THRESHOLD,default=5"` // Ошибок эмбеддингов подряд до паузы записи в память (0 - отключено)
+	EmbeddingBreakerCooldown  time.Duration `env:"EMBEDDING_BREAKER_COOLDOWN,default=5m"` // Длительность паузы записи в память после серии ошибок
 
 	// --- Qdrant Settings ---
 	QdrantEndpoint        string `env:"QDRANT_ENDPOINT,required"`
@@ -66,29 +85,69 @@ type Config struct {
 	QdrantOnDisk          bool   `env:"QDRANT_ON_DISK,default=false"`
 	QdrantQuantizationOn  bool   `env:"QDRANT_QUANTIZATION_ON,default=false"`
 	QdrantQuantizationRam bool   `env:"QDRANT_QUANTIZATION_RAM,default=false"`
+	QdrantDistance        string `env:"QDRANT_DISTANCE,default=cosine"`      // Метрика векторов при создании коллекции: cosine, dot, euclid
+	QdrantHnswM           int    `env:"QDRANT_HNSW_M,default=0"`             // Параметр m индекса HNSW при создании коллекции (0 - по умолчанию)
+	QdrantHnswEfConstruct int    `env:"QDRANT_HNSW_EF_CONSTRUCT,default=0"`  // Параметр ef_construct индекса HNSW при создании коллекции (0 - по умолчанию)
+	QdrantUUIDNamespace   string `env:"QDRANT_UUID_NAMESPACE"`               // UUID-пространство имен для ID точек (пусто - NameSpaceDNS)
+	QdrantCheckDimension  bool   `env:"QDRANT_CHECK_DIMENSION,default=true"` // Проверять при старте размерность существующей коллекции
 
 	// --- Bot Settings ---
-	ResponseTimeoutSec         int           `env:"RESPONSE_TIMEOUT_SEC,default=120"` // Таймаут для ответов Gemini
+	ResponseTimeoutSec         int           `env:"LLM_REQUEST_TIMEOUT_SECONDS,default=120"` // Таймаут для ответов Gemini (устар. RESPONSE_TIMEOUT_SEC)
 	Debug                      bool          `env:"DEBUG,default=false"`
+	ConfigCheck                bool          `env:"CONFIG_CHECK,default=false"`        // Проверить конфигурацию и подключения и завершиться, не запуская бота
+	AllowLLMLessStart          bool          `env:"ALLOW_LLMLESS_START,default=false"` // Запускаться без LLM (только сохранение сообщений), если Gemini недоступен
 	ActivateNewChats           bool          `env:"ACTIVATE_NEW_CHATS,default=true"`
 	RandomReplyEnabled         bool          `env:"RANDOM_REPLY_ENABLED,default=false"`
 	ReplyChance                float32       `env:"REPLY_CHANCE,default=0.1"`
+	AdaptiveTrigger            bool          `env:"ADAPTIVE_TRIGGER,default=false"`            // Масштабировать REPLY_CHANCE по темпу чата
+	AdaptiveTriggerBaseRate    float32       `env:"ADAPTIVE_TRIGGER_BASE_RATE,default=2"`      // Темп (сообщ./мин), при котором REPLY_CHANCE не меняется
+	TriggerCountIncludesMedia  bool          `env:"TRIGGER_COUNT_INCLUDES_MEDIA,default=true"` // Стикеры, фото и голосовые без подписи вызывают случайный ответ и учитываются в темпе чата
 	MaxMessagesForContext      int           `env:"MAX_MESSAGES_FOR_CONTEXT,default=20"`
 	MaxMessagesForSummary      int           `env:"MAX_MESSAGES_FOR_SUMMARY,default=100"`
 	RelevantMessagesCount      int           `env:"RELEVANT_MESSAGES_COUNT,default=5"`
+	ContextStrategy            string        `env:"CONTEXT_STRATEGY,default=interleaved"` // Объединение памяти и недавней истории: recent_only, memory_prefix, interleaved
 	SrachResultCount           int           `env:"SRACH_RESULT_COUNT,default=10"`
 	SummaryCooldown            time.Duration `env:"SUMMARY_COOLDOWN,default=5m"`
 	DirectReplyLimitCount      int           `env:"DIRECT_REPLY_LIMIT_COUNT,default=3"`
 	DirectReplyWindow          time.Duration `env:"DIRECT_REPLY_WINDOW,default=10m"`
-	ContextWindow              int           `env:"CONTEXT_WINDOW,default=50"`     // Для LocalStorage
-	ImportChunkSize            int           `env:"IMPORT_CHUNK_SIZE,default=256"` // Для Qdrant импорта
+	ContextWindow              int           `env:"CONTEXT_WINDOW,default=50"`               // Для LocalStorage
+	ImportChunkSize            int           `env:"IMPORT_CHUNK_SIZE,default=256"`           // Для Qdrant импорта
+	ImportEmbeddingConcurrency int           `env:"IMPORT_EMBEDDING_CONCURRENCY,default=10"` // Параллельных запросов эмбеддингов при импорте
+	ImportVerify               bool          `env:"IMPORT_VERIFY,default=false"`             // Ждать Upsert при импорте и проверять, что точки сохранены
 	MinMessages                int           `env:"MIN_MESSAGES,default=5"`
 	MaxMessages                int           `env:"MAX_MESSAGES,default=15"`
 	DailyTakeTime              int           `env:"DAILY_TAKE_TIME,default=19"` // Час по UTC по умолчанию
-	SummaryIntervalHours       int           `env:"SUMMARY_INTERVAL_HOURS,default=24"`
+	SummaryIntervalHours       int           `env:"SUMMARY_INTERVAL_HOURS,default=0"`
+	SummaryMinNewMessages      int           `env:"SUMMARY_MIN_NEW_MESSAGES,default=10"` // Минимум новых сообщений для авто-саммари
 	SrachKeywordsFile          string        `env:"SRACH_KEYWORDS_FILE,default=srach_keywords.txt"`
 	TimeZone                   string        `env:"TIMEZONE,default=UTC"`
+	QuoteOfDayEnabled          bool          `env:"QUOTE_OF_DAY_ENABLED,default=false"` // Ежедневная "цитата дня" из истории чата
+	QuoteOfDayTime             int           `env:"QUOTE_OF_DAY_TIME,default=12"`       // Час публикации цитаты дня (в TIMEZONE)
+	StatsDigestEnabled         bool          `env:"STATS_DIGEST_ENABLED,default=false"` // Еженедельный дайджест статистики чата
+	StatsDigestWeekday         int           `env:"STATS_DIGEST_WEEKDAY,default=1"`     // День недели дайджеста (0 - воскресенье, 1 - понедельник, ...)
+	StatsDigestTime            int           `env:"STATS_DIGEST_TIME,default=10"`       // Час публикации дайджеста (в TIMEZONE)
+	StatsWindow                time.Duration `env:"STATS_WINDOW,default=24h"`           // Период статистики /stats по умолчанию
 	DirectReplyRateLimitWindow time.Duration `env:"DIRECT_REPLY_RATE_LIMIT_WINDOW,default=10m"`
+	FallbackReplyText          string        `env:"FALLBACK_REPLY_TEXT"`                 // Ответ на прямое обращение, если LLM не ответила (пусто - молчать)
+	FallbackReplyCooldown      time.Duration `env:"FALLBACK_REPLY_COOLDOWN,default=10m"` // Не чаще раза в этот период на чат
+	LLMDailyCallBudget         int           `env:"LLM_DAILY_CALL_BUDGET,default=0"`     // Максимум вызовов LLM за сутки в TIMEZONE (0 - без ограничения)
+	LLMResponseCache           bool          `env:"LLM_RESPONSE_CACHE,default=false"`    // Кешировать ответы на одинаковые произвольные промпты
+	LLMResponseCacheTTL        time.Duration `env:"LLM_RESPONSE_CACHE_TTL,default=1h"`   // Время жизни ответа в кеше
+	DiceReactions              bool          `env:"DICE_REACTIONS,default=false"`        // Отвечать на выдающиеся броски 🎲🎯🏀 (максимальное значение)
+
+	// --- Telegram Send Limits ---
+	TelegramSendRate         int           `env:"TELEGRAM_SEND_RATE,default=30"`          // Максимум исходящих сообщений в секунду на всех чатах
+	TelegramChatSendInterval time.Duration `env:"TELEGRAM_CHAT_SEND_INTERVAL,default=1s"` // Минимальный интервал между сообщениями в один чат
+
+	// --- Startup History Loading (LocalStorage) ---
+	StartupHistoryLoadConcurrency int  `env:"STARTUP_HISTORY_LOAD_CONCURRENCY,default=4"` // Параллельных загрузок историй при старте
+	StartupHistoryMaxMessages     int  `env:"STARTUP_HISTORY_MAX_MESSAGES,default=0"`     // Сообщений на чат при старте (0 - все)
+	LazyHistoryLoad               bool `env:"LAZY_HISTORY_LOAD,default=false"`            // Загружать историю чата при первом сообщении, а не при старте
+	LocalStorageBackup            bool `env:"LOCAL_STORAGE_BACKUP,default=true"`          // Хранить предыдущую версию файлов истории и настроек (.bak)
+	FileStorageGzip               bool `env:"FILE_STORAGE_GZIP,default=false"`            // Сжимать файлы истории чатов (chat_<id>.json.gz)
+	StoreLocation                 bool `env:"STORE_LOCATION,default=false"`               // Сохранять присланные геопозиции и места (координаты - личные данные)
+	StoreContactNames             bool `env:"STORE_CONTACT_NAMES,default=false"`          // Указывать имя присланного контакта (номер телефона не сохраняется никогда)
+	StoreRawUpdates               bool `env:"STORE_RAW_UPDATES,default=false"`            // Сохранять полный JSON сообщения для отладки (/raw)
 
 	// --- Default Generation Settings ---
 	DefaultGenerationSettings          *GenerationSettings
@@ -96,6 +155,7 @@ type Config struct {
 
 	// --- Prompt Templates ---
 	HelpMessage                  string `env:"HELP_MESSAGE"`
+	DefaultRules                 string `env:"DEFAULT_RULES"` // Правила для /rules в чатах без своих правил (/setrules)
 	BaseSystemPrompt             string `env:"BASE_SYSTEM_PROMPT"`
 	DirectReplyPrompt            string `env:"DIRECT_REPLY_PROMPT"`
 	DirectReplyLimitPrompt       string `env:"DIRECT_REPLY_LIMIT_PROMPT"`
@@ -115,6 +175,27 @@ type Config struct {
 	DefaultPrompt                string `env:"DEFAULT_PROMPT"`
 	DirectPrompt                 string `env:"DIRECT_PROMPT"`
 	RateLimitDirectReplyPrompt   string `env:"RATE_LIMIT_DIRECT_REPLY_PROMPT"`
+	// Настроения персоны через запятую (например: саркастичный,ворчливый,восторженный);
+	// к промпту ответа добавляется одно случайное. Пусто - без настроений
+	PersonaMoods []string `env:"PERSONA_MOODS"`
+
+	// --- Word Filter ---
+	WordFilter        []string `env:"WORD_FILTER"`         // Запрещенные слова через запятую (в нижнем регистре); сообщения с ними удаляются
+	WordFilterWarning string   `env:"WORD_FILTER_WARNING"` // Предупреждение автору удаленного сообщения (пусто - удалять молча)
+
+	// --- Welcome ---
+	WelcomeMessage  string        `env:"WELCOME_MESSAGE"`             // Шаблон приветствия новых участников ({{.UserName}} и др.)
+	WelcomePrompt   string        `env:"WELCOME_PROMPT"`              // Промпт для генерации приветствия LLM (пусто - только шаблон)
+	WelcomeCooldown time.Duration `env:"WELCOME_COOLDOWN,default=1m"` // Не чаще одного приветствия в этот период на чат (защита от рейдов)
+
+	// --- Join Flood ---
+	JoinFloodThreshold    int           `env:"JOIN_FLOOD_THRESHOLD,default=0"`      // Вступлений за JOIN_FLOOD_WINDOW, считающихся наплывом (0 - не отслеживать)
+	JoinFloodWindow       time.Duration `env:"JOIN_FLOOD_WINDOW,default=1m"`        // Окно подсчета вступлений
+	JoinFloodAutoMute     bool          `env:"JOIN_FLOOD_AUTO_MUTE,default=false"`  // Запрещать писать участникам, вступившим во время наплыва
+	JoinFloodMuteDuration time.Duration `env:"JOIN_FLOOD_MUTE_DURATION,default=1h"` // На сколько запрещать писать
+
+	// --- Join Captcha ---
+	JoinCaptchaTimeout time.Duration `env:"JOIN_CAPTCHA_TIMEOUT,default=2m"` // Время на проверку вступившего (включается в /settings)
 
 	// --- Внутренние переменные --- (не из env)
 	SrachKeywords []string
@@ -156,6 +237,13 @@ func LoadConfig() (*Config, error) {
 		}
 	}
 
+	// Файл конфигурации (CONFIG_FILE) дополняет окружение: заданные переменные не перезаписываются
+	if configFile := os.Getenv("CONFIG_FILE"); configFile != "" {
+		if err := applyConfigFile(configFile); err != nil {
+			return nil, err
+		}
+	}
+
 	cfg := &Config{}
 
 	// 2. Загрузка обязательных переменных (теперь они должны быть установлены через os.Setenv)
@@ -164,9 +252,13 @@ func LoadConfig() (*Config, error) {
 	if cfg.TelegramToken == "" {
 		return nil, fmt.Errorf("переменная окружения TELEGRAM_BOT_TOKEN обязательна")
 	}
+	cfg.AllowLLMLessStart = getEnvAsBool("ALLOW_LLMLESS_START", false)
 	cfg.GeminiAPIKey = os.Getenv("GEMINI_API_KEY")
 	if cfg.GeminiAPIKey == "" {
-		return nil, fmt.Errorf("переменная окружения GEMINI_API_KEY обязательна")
+		if !cfg.AllowLLMLessStart {
+			return nil, fmt.Errorf("переменная окружения GEMINI_API_KEY обязательна")
+		}
+		log.Println("[Config Load WARN] GEMINI_API_KEY не задан: бот запустится без LLM (ALLOW_LLMLESS_START=true).")
 	}
 	cfg.QdrantEndpoint = os.Getenv("QDRANT_ENDPOINT")
 	if cfg.QdrantEndpoint == "" {
@@ -176,21 +268,68 @@ func LoadConfig() (*Config, error) {
 	// 3. Загрузка остальных переменных с использованием getEnv*
 	cfg.GeminiModelName = getEnv("GEMINI_MODEL_NAME", "gemini-1.5-flash-latest")
 	cfg.GeminiEmbeddingModelName = getEnv("GEMINI_EMBEDDING_MODEL_NAME", "embedding-001")
+	cfg.GeminiFallbackModelName = os.Getenv("GEMINI_FALLBACK_MODEL_NAME")
+	cfg.GeminiSafetyBlockRetry = getEnvAsBool("SAFETY_BLOCK_RETRY", false)
+	for _, model := range strings.Split(os.Getenv("GEMINI_ALLOWED_MODELS"), ",") {
+		if model = strings.TrimSpace(model); model != "" {
+			cfg.GeminiAllowedModels = append(cfg.GeminiAllowedModels, model)
+		}
+	}
+	cfg.EmbeddingProvider = strings.ToLower(strings.TrimSpace(getEnv("EMBEDDING_PROVIDER", EmbeddingProviderGemini)))
+	if err := validateEmbeddingProvider(cfg); err != nil {
+		if !cfg.AllowLLMLessStart {
+			return nil, err
+		}
+		log.Printf("[Config Load WARN] %v. Долговременная память будет недоступна (ALLOW_LLMLESS_START=true).", err)
+	}
+	cfg.EmbeddingBreakerThreshold = getEnvAsInt("EMBEDDING_BREAKER_THRESHOLD", 5)
+	cfg.EmbeddingBreakerCooldown = getEnvAsDuration("EMBEDDING_BREAKER_COOLDOWN", 5*time.Minute)
 	cfg.QdrantAPIKey = os.Getenv("QDRANT_API_KEY") // Может быть пустым
 	cfg.QdrantCollection = getEnv("QDRANT_COLLECTION", "Rofloslav")
 	cfg.QdrantTimeoutSec = getEnvAsInt("QDRANT_TIMEOUT_SEC", 60)
 	cfg.QdrantOnDisk = getEnvAsBool("QDRANT_ON_DISK", false)
 	cfg.QdrantQuantizationOn = getEnvAsBool("QDRANT_QUANTIZATION_ON", false)
 	cfg.QdrantQuantizationRam = getEnvAsBool("QDRANT_QUANTIZATION_RAM", false)
+	cfg.QdrantDistance = strings.ToLower(strings.TrimSpace(getEnv("QDRANT_DISTANCE", "cosine")))
+	cfg.QdrantHnswM = getEnvAsInt("QDRANT_HNSW_M", 0)
+	cfg.QdrantHnswEfConstruct = getEnvAsInt("QDRANT_HNSW_EF_CONSTRUCT", 0)
+	cfg.QdrantUUIDNamespace = strings.TrimSpace(os.Getenv("QDRANT_UUID_NAMESPACE"))
+	cfg.QdrantCheckDimension = getEnvAsBool("QDRANT_CHECK_DIMENSION", true)
+	switch cfg.QdrantDistance {
+	case "cosine", "dot", "euclid":
+	case "":
+		cfg.QdrantDistance = "cosine"
+	default:
+		return nil, fmt.Errorf("неизвестная метрика QDRANT_DISTANCE=%q (доступно: cosine, dot, euclid)", cfg.QdrantDistance)
+	}
 
-	cfg.ResponseTimeoutSec = getEnvAsInt("RESPONSE_TIMEOUT_SEC", 120)
+	// LLM_REQUEST_TIMEOUT_SECONDS имеет приоритет над устаревшим RESPONSE_TIMEOUT_SEC
+	cfg.ResponseTimeoutSec = getEnvAsInt("LLM_REQUEST_TIMEOUT_SECONDS", getEnvAsInt("RESPONSE_TIMEOUT_SEC", 120))
+	if cfg.ResponseTimeoutSec <= 0 {
+		log.Printf("Предупреждение: Таймаут запроса к LLM должен быть положительным (%d). Используется 120.", cfg.ResponseTimeoutSec)
+		cfg.ResponseTimeoutSec = 120
+	}
 	cfg.Debug = getEnvAsBool("DEBUG", false)
 	cfg.ActivateNewChats = getEnvAsBool("ACTIVATE_NEW_CHATS", true)
 	cfg.RandomReplyEnabled = getEnvAsBool("RANDOM_REPLY_ENABLED", false)
 	cfg.ReplyChance = getEnvAsFloat32("REPLY_CHANCE", 0.1)
+	cfg.AdaptiveTrigger = getEnvAsBool("ADAPTIVE_TRIGGER", false)
+	cfg.AdaptiveTriggerBaseRate = getEnvAsFloat32("ADAPTIVE_TRIGGER_BASE_RATE", 2)
+	if cfg.AdaptiveTriggerBaseRate <= 0 {
+		log.Printf("[Config Load WARN] ADAPTIVE_TRIGGER_BASE_RATE=%.2f должно быть > 0, используется 2", cfg.AdaptiveTriggerBaseRate)
+		cfg.AdaptiveTriggerBaseRate = 2
+	}
+	cfg.TriggerCountIncludesMedia = getEnvAsBool("TRIGGER_COUNT_INCLUDES_MEDIA", true)
 	cfg.MaxMessagesForContext = getEnvAsInt("MAX_MESSAGES_FOR_CONTEXT", 20)
 	cfg.MaxMessagesForSummary = getEnvAsInt("MAX_MESSAGES_FOR_SUMMARY", 100)
 	cfg.RelevantMessagesCount = getEnvAsInt("RELEVANT_MESSAGES_COUNT", 5)
+	cfg.ContextStrategy = strings.ToLower(strings.TrimSpace(getEnv("CONTEXT_STRATEGY", ContextStrategyInterleaved)))
+	switch cfg.ContextStrategy {
+	case ContextStrategyRecentOnly, ContextStrategyMemoryPrefix, ContextStrategyInterleaved:
+	default:
+		return nil, fmt.Errorf("неизвестная стратегия CONTEXT_STRATEGY=%q (доступно: %s, %s, %s)",
+			cfg.ContextStrategy, ContextStrategyRecentOnly, ContextStrategyMemoryPrefix, ContextStrategyInterleaved)
+	}
 	cfg.SrachResultCount = getEnvAsInt("SRACH_RESULT_COUNT", 10)
 	cfg.SummaryCooldown = getEnvAsDuration("SUMMARY_COOLDOWN", 5*time.Minute)
 	cfg.DirectReplyLimitCount = getEnvAsInt("DIRECT_REPLY_LIMIT_COUNT", 3)
@@ -198,13 +337,93 @@ func LoadConfig() (*Config, error) {
 
 	// Загрузка устаревших переменных (для информации или плавного перехода)
 	cfg.ContextWindow = getEnvAsInt("CONTEXT_WINDOW", 50)
+	cfg.StartupHistoryLoadConcurrency = getEnvAsInt("STARTUP_HISTORY_LOAD_CONCURRENCY", 4)
+	if cfg.StartupHistoryLoadConcurrency < 1 {
+		log.Printf("[Config Load WARN] STARTUP_HISTORY_LOAD_CONCURRENCY=%d должно быть >= 1, используется 1", cfg.StartupHistoryLoadConcurrency)
+		cfg.StartupHistoryLoadConcurrency = 1
+	}
+	cfg.StartupHistoryMaxMessages = getEnvAsInt("STARTUP_HISTORY_MAX_MESSAGES", 0)
+	if cfg.StartupHistoryMaxMessages < 0 {
+		log.Printf("[Config Load WARN] STARTUP_HISTORY_MAX_MESSAGES=%d не может быть отрицательным, используется 0 (без ограничения)", cfg.StartupHistoryMaxMessages)
+		cfg.StartupHistoryMaxMessages = 0
+	}
+	cfg.LazyHistoryLoad = getEnvAsBool("LAZY_HISTORY_LOAD", false)
+	cfg.TelegramSendRate = getEnvAsInt("TELEGRAM_SEND_RATE", 30)
+	if cfg.TelegramSendRate < 1 {
+		log.Printf("[Config Load WARN] TELEGRAM_SEND_RATE=%d должно быть >= 1, используется 30", cfg.TelegramSendRate)
+		cfg.TelegramSendRate = 30
+	}
+	cfg.TelegramChatSendInterval = getEnvAsDuration("TELEGRAM_CHAT_SEND_INTERVAL", time.Second)
+	if cfg.TelegramChatSendInterval < 0 {
+		log.Printf("[Config Load WARN] TELEGRAM_CHAT_SEND_INTERVAL=%s не может быть отрицательным, используется 1s", cfg.TelegramChatSendInterval)
+		cfg.TelegramChatSendInterval = time.Second
+	}
+	cfg.LocalStorageBackup = getEnvAsBool("LOCAL_STORAGE_BACKUP", true)
+	cfg.FileStorageGzip = getEnvAsBool("FILE_STORAGE_GZIP", false)
+	cfg.StoreLocation = getEnvAsBool("STORE_LOCATION", false)
+	cfg.StoreContactNames = getEnvAsBool("STORE_CONTACT_NAMES", false)
+	cfg.StoreRawUpdates = getEnvAsBool("STORE_RAW_UPDATES", false)
 	cfg.ImportChunkSize = getEnvAsInt("IMPORT_CHUNK_SIZE", 256)
+	cfg.ImportEmbeddingConcurrency = getEnvAsInt("IMPORT_EMBEDDING_CONCURRENCY", 10)
+	if cfg.ImportEmbeddingConcurrency < 1 {
+		log.Printf("[Config Load WARN] IMPORT_EMBEDDING_CONCURRENCY=%d должно быть >= 1, используется 1", cfg.ImportEmbeddingConcurrency)
+		cfg.ImportEmbeddingConcurrency = 1
+	}
+	cfg.ImportVerify = getEnvAsBool("IMPORT_VERIFY", false)
 	cfg.MinMessages = getEnvAsInt("MIN_MESSAGES", 5)
 	cfg.MaxMessages = getEnvAsInt("MAX_MESSAGES", 15)
 	cfg.DailyTakeTime = getEnvAsInt("DAILY_TAKE_TIME", 19)
-	cfg.SummaryIntervalHours = getEnvAsInt("SUMMARY_INTERVAL_HOURS", 24)
+	cfg.SummaryIntervalHours = getEnvAsInt("SUMMARY_INTERVAL_HOURS", 0)
+	cfg.SummaryMinNewMessages = getEnvAsInt("SUMMARY_MIN_NEW_MESSAGES", 10)
+	if cfg.SummaryMinNewMessages < 1 {
+		log.Printf("[Config Load WARN] SUMMARY_MIN_NEW_MESSAGES=%d должно быть >= 1, используется 1", cfg.SummaryMinNewMessages)
+		cfg.SummaryMinNewMessages = 1
+	}
 	cfg.SrachKeywordsFile = getEnv("SRACH_KEYWORDS_FILE", "srach_keywords.txt")
-	cfg.TimeZone = getEnv("TIMEZONE", "UTC")
+	cfg.ConfigCheck = getEnvAsBool("CONFIG_CHECK", false)
+	cfg.FallbackReplyText = os.Getenv("FALLBACK_REPLY_TEXT")
+	cfg.FallbackReplyCooldown = getEnvAsDuration("FALLBACK_REPLY_COOLDOWN", 10*time.Minute)
+	cfg.LLMResponseCache = getEnvAsBool("LLM_RESPONSE_CACHE", false)
+	cfg.LLMResponseCacheTTL = getEnvAsDuration("LLM_RESPONSE_CACHE_TTL", time.Hour)
+	if cfg.LLMResponseCacheTTL <= 0 {
+		log.Printf("[Config Load WARN] LLM_RESPONSE_CACHE_TTL=%s должно быть > 0, используется 1h", cfg.LLMResponseCacheTTL)
+		cfg.LLMResponseCacheTTL = time.Hour
+	}
+	cfg.DiceReactions = getEnvAsBool("DICE_REACTIONS", false)
+	cfg.LLMDailyCallBudget = getEnvAsInt("LLM_DAILY_CALL_BUDGET", 0)
+	if cfg.LLMDailyCallBudget < 0 {
+		log.Printf("[Config Load WARN] LLM_DAILY_CALL_BUDGET=%d не может быть отрицательным, используется 0 (без ограничения)", cfg.LLMDailyCallBudget)
+		cfg.LLMDailyCallBudget = 0
+	}
+	// TIME_ZONE - прежнее имя переменной, используется, если TIMEZONE не задан. По умолчанию - UTC
+	cfg.TimeZone = getEnv("TIMEZONE", "")
+	if legacy, ok := os.LookupEnv("TIME_ZONE"); ok && cfg.TimeZone == "" {
+		log.Printf("[Config Load WARN] TIMEZONE не задан, используется устаревшая переменная TIME_ZONE=%q. Переименуйте ее в TIMEZONE", legacy)
+		cfg.TimeZone = legacy
+	}
+	normalizeTimeZone(cfg)
+	cfg.QuoteOfDayEnabled = getEnvAsBool("QUOTE_OF_DAY_ENABLED", false)
+	cfg.QuoteOfDayTime = getEnvAsInt("QUOTE_OF_DAY_TIME", 12)
+	if cfg.QuoteOfDayTime < 0 || cfg.QuoteOfDayTime > 23 {
+		log.Printf("[Config Load WARN] QUOTE_OF_DAY_TIME=%d вне диапазона 0-23, используется 12", cfg.QuoteOfDayTime)
+		cfg.QuoteOfDayTime = 12
+	}
+	cfg.StatsDigestEnabled = getEnvAsBool("STATS_DIGEST_ENABLED", false)
+	cfg.StatsDigestWeekday = getEnvAsInt("STATS_DIGEST_WEEKDAY", 1)
+	if cfg.StatsDigestWeekday < 0 || cfg.StatsDigestWeekday > 6 {
+		log.Printf("[Config Load WARN] STATS_DIGEST_WEEKDAY=%d вне диапазона 0-6, используется 1 (понедельник)", cfg.StatsDigestWeekday)
+		cfg.StatsDigestWeekday = 1
+	}
+	cfg.StatsDigestTime = getEnvAsInt("STATS_DIGEST_TIME", 10)
+	if cfg.StatsDigestTime < 0 || cfg.StatsDigestTime > 23 {
+		log.Printf("[Config Load WARN] STATS_DIGEST_TIME=%d вне диапазона 0-23, используется 10", cfg.StatsDigestTime)
+		cfg.StatsDigestTime = 10
+	}
+	cfg.StatsWindow = getEnvAsDuration("STATS_WINDOW", 24*time.Hour)
+	if cfg.StatsWindow <= 0 {
+		log.Printf("[Config Load WARN] STATS_WINDOW=%s должно быть > 0, используется 24h", cfg.StatsWindow)
+		cfg.StatsWindow = 24 * time.Hour
+	}
 
 	// Загрузка списка Admin User IDs
 	adminIDsStr := os.Getenv("ADMIN_USER_IDS")
@@ -232,6 +451,35 @@ func LoadConfig() (*Config, error) {
 	if len(cfg.AdminUserIDs) == 0 {
 		log.Println("Предупреждение: Список ADMIN_USER_IDS пуст. Некоторые команды могут быть недоступны.")
 	}
+	if notifyChatStr := strings.TrimSpace(os.Getenv("ADMIN_NOTIFY_CHAT_ID")); notifyChatStr != "" {
+		if id, err := strconv.ParseInt(notifyChatStr, 10, 64); err == nil {
+			cfg.AdminNotifyChatID = id
+		} else {
+			log.Printf("Предупреждение: Неверный формат ADMIN_NOTIFY_CHAT_ID: %s. Оповещения отключены.", notifyChatStr)
+		}
+	}
+	cfg.AdminNotifyCooldown = getEnvAsDuration("ADMIN_NOTIFY_COOLDOWN", 30*time.Minute)
+
+	// Загрузка списка игнорируемых пользователей
+	if ignoredIDsStr := os.Getenv("IGNORED_USER_IDS"); ignoredIDsStr != "" {
+		for _, idStr := range strings.Split(ignoredIDsStr, ",") {
+			idStr = strings.TrimSpace(idStr)
+			if idStr == "" {
+				continue
+			}
+			if id, err := strconv.ParseInt(idStr, 10, 64); err == nil {
+				cfg.IgnoredUserIDs = append(cfg.IgnoredUserIDs, id)
+			} else {
+				log.Printf("[Config Load WARN] Неверный формат ID в IGNORED_USER_IDS: %s", idStr)
+			}
+		}
+	}
+	cfg.IgnoreOtherBots = getEnvAsBool("IGNORE_OTHER_BOTS", true)
+	cfg.CommandPrefix = strings.TrimSpace(getEnv("COMMAND_PREFIX", ""))
+	if cfg.CommandPrefix == "/" {
+		cfg.CommandPrefix = ""
+	}
+	cfg.IgnoreForeignCommands = getEnvAsBool("IGNORE_FOREIGN_COMMANDS", true)
 
 	// 4. Инициализация настроек генерации по умолчанию
 	cfg.DefaultGenerationSettings = &GenerationSettings{
@@ -250,11 +498,52 @@ func LoadConfig() (*Config, error) {
 	}
 
 	// 5. Загрузка Prompt Templates
-	cfg.HelpMessage = getEnv("HELP_MESSAGE", "Привет! Я бот для чата. Команды: /activate, /deactivate, /status, /summarize, /srach [запрос]")
-	cfg.BaseSystemPrompt = getEnv("BASE_SYSTEM_PROMPT", "Ты - участник группового чата.")
-	cfg.DirectReplyPrompt = getEnv("DIRECT_REPLY_PROMPT", "Тебе адресовали сообщение:")
-	cfg.DirectReplyLimitPrompt = getEnv("DIRECT_REPLY_LIMIT_PROMPT", "Вы слишком часто пишете мне.")
-	cfg.SummaryPrompt = getEnv("SUMMARY_PROMPT", "Подведи итог этого диалога кратко:")
+	cfg.HelpMessage = getEnv("HELP_MESSAGE", "Привет! Я бот для чата. Команды: /activate, /deactivate, /status, /settings, /summarize, /summary_since [период], /srach [запрос], /random, /stats [период]")
+	cfg.DefaultRules = os.Getenv("DEFAULT_RULES")
+	// Пустые промпты заменяются промптами языка чата (/setlang)
+	cfg.BaseSystemPrompt = os.Getenv("BASE_SYSTEM_PROMPT")
+	cfg.DirectReplyPrompt = os.Getenv("DIRECT_REPLY_PROMPT")
+	cfg.DirectReplyLimitPrompt = os.Getenv("DIRECT_REPLY_LIMIT_PROMPT")
+	cfg.SummaryPrompt = os.Getenv("SUMMARY_PROMPT")
+	for _, word := range strings.Split(os.Getenv("WORD_FILTER"), ",") {
+		if word = strings.ToLower(strings.TrimSpace(word)); word != "" {
+			cfg.WordFilter = append(cfg.WordFilter, word)
+		}
+	}
+	cfg.WordFilterWarning = getEnv("WORD_FILTER_WARNING", "сообщение удалено: запрещенные слова.")
+	cfg.JoinFloodThreshold = getEnvAsInt("JOIN_FLOOD_THRESHOLD", 0)
+	if cfg.JoinFloodThreshold < 0 {
+		log.Printf("[Config Load WARN] JOIN_FLOOD_THRESHOLD=%d не может быть отрицательным, используется 0 (не отслеживать)", cfg.JoinFloodThreshold)
+		cfg.JoinFloodThreshold = 0
+	}
+	cfg.JoinFloodWindow = getEnvAsDuration("JOIN_FLOOD_WINDOW", time.Minute)
+	if cfg.JoinFloodWindow <= 0 {
+		log.Printf("[Config Load WARN] JOIN_FLOOD_WINDOW=%s должно быть > 0, используется 1m", cfg.JoinFloodWindow)
+		cfg.JoinFloodWindow = time.Minute
+	}
+	cfg.JoinFloodAutoMute = getEnvAsBool("JOIN_FLOOD_AUTO_MUTE", false)
+	cfg.JoinFloodMuteDuration = getEnvAsDuration("JOIN_FLOOD_MUTE_DURATION", time.Hour)
+	if cfg.JoinFloodMuteDuration <= 0 {
+		log.Printf("[Config Load WARN] JOIN_FLOOD_MUTE_DURATION=%s должно быть > 0, используется 1h", cfg.JoinFloodMuteDuration)
+		cfg.JoinFloodMuteDuration = time.Hour
+	}
+	cfg.JoinCaptchaTimeout = getEnvAsDuration("JOIN_CAPTCHA_TIMEOUT", 2*time.Minute)
+	if cfg.JoinCaptchaTimeout <= 0 {
+		log.Printf("[Config Load WARN] JOIN_CAPTCHA_TIMEOUT=%s должно быть > 0, используется 2m", cfg.JoinCaptchaTimeout)
+		cfg.JoinCaptchaTimeout = 2 * time.Minute
+	}
+	cfg.WelcomeMessage = getEnv("WELCOME_MESSAGE", "Добро пожаловать, {{.UserName}}!")
+	cfg.WelcomePrompt = os.Getenv("WELCOME_PROMPT")
+	cfg.WelcomeCooldown = getEnvAsDuration("WELCOME_COOLDOWN", time.Minute)
+	if cfg.WelcomeCooldown < 0 {
+		log.Printf("[Config Load WARN] WELCOME_COOLDOWN=%s не может быть отрицательным, используется 1m", cfg.WelcomeCooldown)
+		cfg.WelcomeCooldown = time.Minute
+	}
+	for _, mood := range strings.Split(os.Getenv("PERSONA_MOODS"), ",") {
+		if mood = strings.TrimSpace(mood); mood != "" {
+			cfg.PersonaMoods = append(cfg.PersonaMoods, mood)
+		}
+	}
 	cfg.DailyTakePrompt = os.Getenv("DAILY_TAKE_PROMPT")
 	cfg.SummaryRateLimitInsultPrompt = os.Getenv("SUMMARY_RATE_LIMIT_INSULT_PROMPT")
 	cfg.SummaryRateLimitStaticPrefix = os.Getenv("SUMMARY_RATE_LIMIT_STATIC_PREFIX")
@@ -282,6 +571,55 @@ func LoadConfig() (*Config, error) {
 	return cfg, nil
 }
 
+// Стратегии объединения долговременной памяти с недавней историей (CONTEXT_STRATEGY).
+const (
+	ContextStrategyRecentOnly   = "recent_only"   // Только недавние сообщения, без поиска по памяти
+	ContextStrategyMemoryPrefix = "memory_prefix" // Блок из памяти перед недавними сообщениями
+	ContextStrategyInterleaved  = "interleaved"   // Память и недавние сообщения вперемешку по времени
+)
+
+// EmbeddingProviderGemini - провайдер эмбеддингов Gemini (единственный поддерживаемый на данный момент).
+const EmbeddingProviderGemini = "gemini"
+
+// supportedEmbeddingProviders - провайдеры, для которых есть реализация получения эмбеддингов.
+var supportedEmbeddingProviders = []string{EmbeddingProviderGemini}
+
+// validateEmbeddingProvider проверяет, что выбранный провайдер эмбеддингов поддерживается и настроен.
+// Долговременная память (Qdrant) всегда требует эмбеддингов, поэтому ошибка здесь фатальна.
+func validateEmbeddingProvider(cfg *Config) error {
+	supported := false
+	for _, p := range supportedEmbeddingProviders {
+		if cfg.EmbeddingProvider == p {
+			supported = true
+			break
+		}
+	}
+	if !supported {
+		return fmt.Errorf("EMBEDDING_PROVIDER=%q не поддерживается (доступно: %s)", cfg.EmbeddingProvider, strings.Join(supportedEmbeddingProviders, ", "))
+	}
+	if cfg.EmbeddingProvider == EmbeddingProviderGemini && (cfg.GeminiAPIKey == "" || cfg.GeminiEmbeddingModelName == "") {
+		return fmt.Errorf("для EMBEDDING_PROVIDER=gemini необходимы GEMINI_API_KEY и GEMINI_EMBEDDING_MODEL_NAME")
+	}
+	return nil
+}
+
+// normalizeTimeZone проверяет часовой пояс через time.LoadLocation при загрузке конфига.
+// Некорректное значение (опечатка) заменяется на UTC с предупреждением, чтобы планировщики
+// не срабатывали в неожиданное время молча.
+func normalizeTimeZone(cfg *Config) {
+	cfg.TimeZone = strings.TrimSpace(cfg.TimeZone)
+	if cfg.TimeZone == "" {
+		cfg.TimeZone = "UTC"
+	}
+	loc, err := time.LoadLocation(cfg.TimeZone)
+	if err != nil {
+		log.Printf("[Config Load WARN] Неизвестный часовой пояс TIMEZONE=%q (%v), используется UTC", cfg.TimeZone, err)
+		cfg.TimeZone = "UTC"
+		return
+	}
+	cfg.TimeZone = loc.String()
+}
+
 // --- Вспомогательные функции для загрузки переменных окружения ---
 
 func getEnv(key, fallback string) string {
@@ -372,27 +710,68 @@ func logConfig(cfg *Config) {
 	log.Printf("[Config Load] Debug: %t", cfg.Debug)
 	log.Printf("[Config Load] Activate New Chats: %t", cfg.ActivateNewChats)
 	log.Printf("[Config Load] Random Reply Enabled: %t (Chance: %.2f)", cfg.RandomReplyEnabled, cfg.ReplyChance)
+	log.Printf("[Config Load] Adaptive Trigger: %t (Base Rate: %.2f msg/min)", cfg.AdaptiveTrigger, cfg.AdaptiveTriggerBaseRate)
+	log.Printf("[Config Load] Trigger Count Includes Media: %t", cfg.TriggerCountIncludesMedia)
 	log.Printf("[Config Load] Gemini Model: %s", cfg.GeminiModelName)
 	log.Printf("[Config Load] Gemini Embedding Model: %s", cfg.GeminiEmbeddingModelName)
+	log.Printf("[Config Load] Gemini Fallback Model: %s", cfg.GeminiFallbackModelName)
+	log.Printf("[Config Load] Gemini Allowed Models: %v", cfg.GeminiAllowedModels)
+	log.Printf("[Config Load] Safety Block Retry: %t", cfg.GeminiSafetyBlockRetry)
+	log.Printf("[Config Load] Embedding Provider: %s", cfg.EmbeddingProvider)
+	log.Printf("[Config Load] Embedding Breaker: порог %d, пауза %s", cfg.EmbeddingBreakerThreshold, cfg.EmbeddingBreakerCooldown)
 	log.Printf("[Config Load] Qdrant Endpoint: %s", cfg.QdrantEndpoint)
 	log.Printf("[Config Load] Qdrant Collection: %s", cfg.QdrantCollection)
 	log.Printf("[Config Load] Qdrant Timeout (sec): %d", cfg.QdrantTimeoutSec)
 	log.Printf("[Config Load] Qdrant OnDisk: %t, Quantization: %t (RAM: %t)", cfg.QdrantOnDisk, cfg.QdrantQuantizationOn, cfg.QdrantQuantizationRam)
+	log.Printf("[Config Load] Qdrant Distance: %s", cfg.QdrantDistance)
+	log.Printf("[Config Load] Qdrant HNSW: m=%d, ef_construct=%d", cfg.QdrantHnswM, cfg.QdrantHnswEfConstruct)
+	log.Printf("[Config Load] Qdrant UUID Namespace: %s", cfg.QdrantUUIDNamespace)
+	log.Printf("[Config Load] Qdrant Check Dimension: %t", cfg.QdrantCheckDimension)
 	log.Printf("[Config Load] Response Timeout (sec): %d", cfg.ResponseTimeoutSec)
 	log.Printf("[Config Load] Max Messages for Context: %d", cfg.MaxMessagesForContext)
 	log.Printf("[Config Load] Max Messages for Summary: %d", cfg.MaxMessagesForSummary)
 	log.Printf("[Config Load] Relevant Messages Count (Search): %d", cfg.RelevantMessagesCount)
+	log.Printf("[Config Load] Context Strategy: %s", cfg.ContextStrategy)
 	log.Printf("[Config Load] Srach Result Count (Search): %d", cfg.SrachResultCount)
 	log.Printf("[Config Load] Summary Cooldown: %v", cfg.SummaryCooldown)
 	log.Printf("[Config Load] Daily Take Time: %d:00 (%s)", cfg.DailyTakeTime, cfg.TimeZone)
+	log.Printf("[Config Load] Quote of the Day: %t (%d:00)", cfg.QuoteOfDayEnabled, cfg.QuoteOfDayTime)
+	log.Printf("[Config Load] Stats Digest: %t (weekday %d, %d:00)", cfg.StatsDigestEnabled, cfg.StatsDigestWeekday, cfg.StatsDigestTime)
+	log.Printf("[Config Load] Stats Window: %s", cfg.StatsWindow)
 	log.Printf("[Config Load] Summary Interval (hours): %d", cfg.SummaryIntervalHours)
+	log.Printf("[Config Load] Summary Min New Messages: %d", cfg.SummaryMinNewMessages)
 	log.Printf("[Config Load] Srach Keywords File: %s (loaded: %d)", cfg.SrachKeywordsFile, len(cfg.SrachKeywords))
 	log.Printf("[Config Load] Direct Reply Limit: %d requests per %v", cfg.DirectReplyLimitCount, cfg.DirectReplyWindow)
 	log.Printf("[Config Load] Admin IDs: %v", cfg.AdminUserIDs)
+	log.Printf("[Config Load] Admin Notify Chat ID: %d (cooldown %s)", cfg.AdminNotifyChatID, cfg.AdminNotifyCooldown)
+	log.Printf("[Config Load] Ignored User IDs: %v", cfg.IgnoredUserIDs)
+	log.Printf("[Config Load] Ignore Other Bots: %t", cfg.IgnoreOtherBots)
+	log.Printf("[Config Load] Command Prefix: %q, Ignore Foreign Commands: %t", cfg.CommandPrefix, cfg.IgnoreForeignCommands)
+	log.Printf("[Config Load] Allow LLM-less Start: %t", cfg.AllowLLMLessStart)
+	log.Printf("[Config Load] Fallback Reply: %t (cooldown %s)", cfg.FallbackReplyText != "", cfg.FallbackReplyCooldown)
+	log.Printf("[Config Load] LLM Daily Call Budget: %d", cfg.LLMDailyCallBudget)
+	log.Printf("[Config Load] LLM Response Cache: %t (TTL %s)", cfg.LLMResponseCache, cfg.LLMResponseCacheTTL)
+	log.Printf("[Config Load] Dice Reactions: %t", cfg.DiceReactions)
 	log.Printf("[Config Load] Help Message Loaded: %t", cfg.HelpMessage != "")
+	log.Printf("[Config Load] Default Rules Loaded: %t", cfg.DefaultRules != "")
+	log.Printf("[Config Load] Persona Moods: %v", cfg.PersonaMoods)
+	log.Printf("[Config Load] Welcome: промпт LLM %t, кулдаун %s", cfg.WelcomePrompt != "", cfg.WelcomeCooldown)
+	log.Printf("[Config Load] Join Flood: порог %d за %s (авто-мут: %t на %s)", cfg.JoinFloodThreshold, cfg.JoinFloodWindow, cfg.JoinFloodAutoMute, cfg.JoinFloodMuteDuration)
+	log.Printf("[Config Load] Join Captcha Timeout: %s", cfg.JoinCaptchaTimeout)
+	log.Printf("[Config Load] Word Filter: %d слов (предупреждение: %t)", len(cfg.WordFilter), cfg.WordFilterWarning != "")
 	// Логирование устаревших полей для информации
 	log.Printf("[Config Load] (Legacy) Context Window: %d", cfg.ContextWindow)
+	log.Printf("[Config Load] Startup History Load: concurrency %d, max messages %d", cfg.StartupHistoryLoadConcurrency, cfg.StartupHistoryMaxMessages)
+	log.Printf("[Config Load] Lazy History Load: %t", cfg.LazyHistoryLoad)
+	log.Printf("[Config Load] Telegram Send Rate: %d/s, Chat Send Interval: %s", cfg.TelegramSendRate, cfg.TelegramChatSendInterval)
+	log.Printf("[Config Load] Local Storage Backup: %t", cfg.LocalStorageBackup)
+	log.Printf("[Config Load] File Storage Gzip: %t", cfg.FileStorageGzip)
+	log.Printf("[Config Load] Store Location: %t", cfg.StoreLocation)
+	log.Printf("[Config Load] Store Contact Names: %t", cfg.StoreContactNames)
+	log.Printf("[Config Load] Store Raw Updates: %t", cfg.StoreRawUpdates)
 	log.Printf("[Config Load] (Legacy) Import Chunk Size: %d", cfg.ImportChunkSize)
+	log.Printf("[Config Load] Import Embedding Concurrency: %d", cfg.ImportEmbeddingConcurrency)
+	log.Printf("[Config Load] Import Verify: %t", cfg.ImportVerify)
 	log.Printf("[Config Load] (Legacy) Min/Max Messages: %d/%d", cfg.MinMessages, cfg.MaxMessages)
 	log.Printf("[Config Load] (Legacy) DirectReplyRateLimitWindow: %v", cfg.DirectReplyRateLimitWindow)
 }
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+)
+
+// applyConfigFile читает JSON-файл конфигурации (CONFIG_FILE) и устанавливает из него переменные
+// окружения, которые еще не заданы. Ключи файла - имена переменных окружения (см. теги env в Config),
+// поэтому значения проходят тот же разбор и проверки, что и переменные из .env.
+// Переменные окружения и .env имеют приоритет над файлом.
+//
+// Пример:
+//
+//	{
+//	  "GEMINI_MODEL_NAME": "gemini-2.0-flash",
+//	  "RANDOM_REPLY_ENABLED": true,
+//	  "REPLY_CHANCE": 0.05,
+//	  "ADMIN_USER_IDS": "123,456"
+//	}
+//
+// Объекты и массивы записываются в переменную как JSON-строка (удобно для значений в формате JSON).
+func applyConfigFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("ошибка чтения файла конфигурации '%s': %w", path, err)
+	}
+
+	var values map[string]interface{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return fmt.Errorf("ошибка разбора JSON в файле конфигурации '%s': %w", path, err)
+	}
+
+	applied := 0
+	for key, raw := range values {
+		if _, exists := os.LookupEnv(key); exists {
+			continue // Переменная окружения переопределяет значение из файла
+		}
+		value, err := configFileValue(raw)
+		if err != nil {
+			return fmt.Errorf("некорректное значение %s в файле конфигурации: %w", key, err)
+		}
+		if err := os.Setenv(key, value); err != nil {
+			log.Printf("Предупреждение: Не удалось установить переменную окружения из %s: %s (%v)", path, key, err)
+			continue
+		}
+		applied++
+	}
+	log.Printf("[Config Load] Файл конфигурации %s прочитан: применено %d из %d значений (остальные заданы в окружении).", path, applied, len(values))
+	return nil
+}
+
+// configFileValue приводит значение из JSON к строке в формате переменной окружения.
+func configFileValue(raw interface{}) (string, error) {
+	switch v := raw.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		return string(encoded), nil
+	}
+}
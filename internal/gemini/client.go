@@ -2,9 +2,11 @@ package gemini
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
+	"time"
 
 	"github.com/Henry-Case-dev/rofloslav/internal/config" // Импорт для config.GenerationSettings и др.
 	genai "github.com/google/generative-ai-go/genai"
@@ -16,12 +18,22 @@ import (
 type Client struct {
 	generativeClient   *genai.Client
 	modelName          string
-	embeddingModelName string // Добавлено поле для имени модели эмбеддингов
+	embeddingModelName string        // Добавлено поле для имени модели эмбеддингов
+	requestTimeout     time.Duration // Таймаут запроса, если у переданного контекста нет дедлайна
+	safetyBlockRetry   bool          // Повторять заблокированный фильтром безопасности запрос с BLOCK_NONE
 }
 
+// ErrTimeout возвращается, если запрос к Gemini не уложился в отведенное время.
+var ErrTimeout = errors.New("превышено время ожидания ответа Gemini")
+
+// ErrRateLimited возвращается, если Gemini отклонил запрос из-за лимита частоты или исчерпанной квоты (429).
+var ErrRateLimited = errors.New("превышен лимит запросов или квота Gemini")
+
 // NewClient создает и инициализирует нового клиента Gemini.
 // Используем modelName для генерации контента и embeddingModelName для эмбеддингов.
-func NewClient(ctx context.Context, apiKey, modelName, embeddingModelName string) (*Client, error) {
+// requestTimeout ограничивает каждый запрос, если вызывающий код не задал дедлайн сам (0 - без ограничения).
+// safetyBlockRetry включает повтор заблокированных фильтром безопасности запросов (SAFETY_BLOCK_RETRY).
+func NewClient(ctx context.Context, apiKey, modelName, embeddingModelName string, requestTimeout time.Duration, safetyBlockRetry bool) (*Client, error) {
 	log.Printf("Инициализация клиента Gemini для модели генерации: %s и модели эмбеддингов: %s", modelName, embeddingModelName)
 	generativeClient, err := genai.NewClient(ctx, option.WithAPIKey(apiKey))
 	if err != nil {
@@ -33,6 +45,8 @@ func NewClient(ctx context.Context, apiKey, modelName, embeddingModelName string
 		generativeClient:   generativeClient,
 		modelName:          modelName,
 		embeddingModelName: embeddingModelName, // Сохраняем имя модели эмбеддингов
+		requestTimeout:     requestTimeout,
+		safetyBlockRetry:   safetyBlockRetry,
 	}, nil
 }
 
@@ -94,10 +108,15 @@ func (c *Client) GetEmbeddingsBatch(ctx context.Context, texts []string) ([][]fl
 	// Логируем первый текст для примера (если батч не пустой)
 	log.Printf("[Gemini DEBUG] GetEmbeddingsBatch: Пример текста для эмбеддинга: \"%s...\"", truncateString(texts[0], 100))
 
-	res, err := em.BatchEmbedContents(ctx, batch)
+	reqCtx, cancel := c.withRequestTimeout(ctx)
+	defer cancel()
+	res, err := em.BatchEmbedContents(reqCtx, batch)
 	if err != nil {
+		err = wrapRateLimitError(wrapTimeoutError(reqCtx, err))
 		// Проверяем на специфичную ошибку квоты
-		if strings.Contains(err.Error(), "429") {
+		if errors.Is(err, ErrTimeout) {
+			log.Printf("[Gemini ERROR TIMEOUT] GetEmbeddingsBatch: Превышено время ожидания эмбеддингов: %v", err)
+		} else if errors.Is(err, ErrRateLimited) {
 			log.Printf("[Gemini ERROR QUOTA] GetEmbeddingsBatch: Достигнута квота API Gemini при получении эмбеддингов: %v", err)
 		} else {
 			log.Printf("[Gemini ERROR] GetEmbeddingsBatch: Ошибка при получении эмбеддингов: %v", err)
@@ -127,12 +146,22 @@ func (c *Client) GetEmbeddingsBatch(ctx context.Context, texts []string) ([][]fl
 	return embeddings, nil
 }
 
+// ModelName возвращает имя основной модели генерации.
+func (c *Client) ModelName() string {
+	return c.modelName
+}
+
 // GenerateContent генерирует текст на основе промпта и истории сообщений.
 // Используем *config.GenerationSettings
 func (c *Client) GenerateContent(ctx context.Context, systemPrompt string, history []*genai.Content, lastMessage string, settings *config.GenerationSettings) (string, error) {
-	log.Printf("[Gemini DEBUG] GenerateContent: Запрос на генерацию контента. SystemPrompt: \"%s...\", History len: %d, LastMessage: \"%s...\"", truncateString(systemPrompt, 50), len(history), truncateString(lastMessage, 50))
+	return c.GenerateContentWithModel(ctx, c.modelName, systemPrompt, history, lastMessage, settings)
+}
+
+// GenerateContentWithModel - то же, что GenerateContent, но с явно указанной моделью.
+func (c *Client) GenerateContentWithModel(ctx context.Context, modelName string, systemPrompt string, history []*genai.Content, lastMessage string, settings *config.GenerationSettings) (string, error) {
+	log.Printf("[Gemini DEBUG] GenerateContent: Запрос на генерацию контента (модель %s). SystemPrompt: \"%s...\", History len: %d, LastMessage: \"%s...\"", modelName, truncateString(systemPrompt, 50), len(history), truncateString(lastMessage, 50))
 
-	genaiModel := c.generativeClient.GenerativeModel(c.modelName)
+	genaiModel := c.generativeClient.GenerativeModel(modelName)
 
 	// Настройки через GenerationConfig
 	genaiModel.GenerationConfig = genai.GenerationConfig{}
@@ -177,10 +206,22 @@ func (c *Client) GenerateContent(ctx context.Context, systemPrompt string, histo
 	cs.History = contents // Устанавливаем историю сессии
 
 	// Отправляем пустой запрос, чтобы получить ответ модели на основе истории
-	resp, err := cs.SendMessage(ctx /* Пустая часть */)
+	reqCtx, cancel := c.withRequestTimeout(ctx)
+	defer cancel()
+	resp, err := cs.SendMessage(reqCtx /* Пустая часть */)
+	if c.shouldRetrySafetyBlock(genaiModel, err) {
+		log.Printf("[Gemini WARN] GenerateContent: Ответ модели %s заблокирован фильтром безопасности (%v). Повторяем с порогом BLOCK_NONE.", modelName, err)
+		genaiModel.SafetySettings = relaxedSafetySettings()
+		cs = genaiModel.StartChat()
+		cs.History = contents
+		resp, err = cs.SendMessage(reqCtx)
+	}
 
 	if err != nil {
-		if strings.Contains(err.Error(), "429") {
+		err = wrapRateLimitError(wrapTimeoutError(reqCtx, err))
+		if errors.Is(err, ErrTimeout) {
+			log.Printf("[Gemini ERROR TIMEOUT] GenerateContent: Превышено время ожидания ответа: %v", err)
+		} else if errors.Is(err, ErrRateLimited) {
 			log.Printf("[Gemini ERROR QUOTA] GenerateContent: Достигнута квота API Gemini: %v", err)
 		} else {
 			log.Printf("[Gemini ERROR] GenerateContent: Ошибка генерации контента: %v", err)
@@ -197,8 +238,13 @@ func (c *Client) GenerateContent(ctx context.Context, systemPrompt string, histo
 // GenerateArbitraryContent генерирует текст на основе произвольного промпта (без истории).
 // Используем *config.ArbitraryGenerationSettings
 func (c *Client) GenerateArbitraryContent(ctx context.Context, prompt string, settings *config.ArbitraryGenerationSettings) (string, error) {
-	log.Printf("[Gemini DEBUG] GenerateArbitraryContent: Запрос на генерацию. Prompt: \"%s...\"", truncateString(prompt, 100))
-	genaiModel := c.generativeClient.GenerativeModel(c.modelName)
+	return c.GenerateArbitraryContentWithModel(ctx, c.modelName, prompt, settings)
+}
+
+// GenerateArbitraryContentWithModel - то же, что GenerateArbitraryContent, но с явно указанной моделью.
+func (c *Client) GenerateArbitraryContentWithModel(ctx context.Context, modelName string, prompt string, settings *config.ArbitraryGenerationSettings) (string, error) {
+	log.Printf("[Gemini DEBUG] GenerateArbitraryContent: Запрос на генерацию (модель %s). Prompt: \"%s...\"", modelName, truncateString(prompt, 100))
+	genaiModel := c.generativeClient.GenerativeModel(modelName)
 
 	// Настройки через GenerationConfig
 	genaiModel.GenerationConfig = genai.GenerationConfig{}
@@ -220,9 +266,19 @@ func (c *Client) GenerateArbitraryContent(ctx context.Context, prompt string, se
 		}
 	}
 
-	resp, err := genaiModel.GenerateContent(ctx, genai.Text(prompt))
+	reqCtx, cancel := c.withRequestTimeout(ctx)
+	defer cancel()
+	resp, err := genaiModel.GenerateContent(reqCtx, genai.Text(prompt))
+	if c.shouldRetrySafetyBlock(genaiModel, err) {
+		log.Printf("[Gemini WARN] GenerateArbitraryContent: Ответ модели %s заблокирован фильтром безопасности (%v). Повторяем с порогом BLOCK_NONE.", modelName, err)
+		genaiModel.SafetySettings = relaxedSafetySettings()
+		resp, err = genaiModel.GenerateContent(reqCtx, genai.Text(prompt))
+	}
 	if err != nil {
-		if strings.Contains(err.Error(), "429") {
+		err = wrapRateLimitError(wrapTimeoutError(reqCtx, err))
+		if errors.Is(err, ErrTimeout) {
+			log.Printf("[Gemini ERROR TIMEOUT] GenerateArbitraryContent: Превышено время ожидания ответа: %v", err)
+		} else if errors.Is(err, ErrRateLimited) {
 			log.Printf("[Gemini ERROR QUOTA] GenerateArbitraryContent: Достигнута квота API Gemini: %v", err)
 		} else {
 			log.Printf("[Gemini ERROR] GenerateArbitraryContent: Ошибка генерации: %v", err)
@@ -238,6 +294,67 @@ func (c *Client) GenerateArbitraryContent(ctx context.Context, prompt string, se
 
 // --- Вспомогательные функции ---
 
+// safetyCategories - категории фильтра безопасности, которые принимает Gemini API.
+var safetyCategories = []genai.HarmCategory{
+	genai.HarmCategoryHarassment,
+	genai.HarmCategoryHateSpeech,
+	genai.HarmCategorySexuallyExplicit,
+	genai.HarmCategoryDangerousContent,
+}
+
+// relaxedSafetySettings возвращает настройки безопасности с порогом BLOCK_NONE для всех категорий.
+func relaxedSafetySettings() []*genai.SafetySetting {
+	settings := make([]*genai.SafetySetting, 0, len(safetyCategories))
+	for _, category := range safetyCategories {
+		settings = append(settings, &genai.SafetySetting{Category: category, Threshold: genai.HarmBlockNone})
+	}
+	return settings
+}
+
+// shouldRetrySafetyBlock проверяет, нужно ли повторить запрос с BLOCK_NONE: повтор включен (SAFETY_BLOCK_RETRY),
+// ответ или промпт заблокирован именно фильтром безопасности и модель еще не использует BLOCK_NONE.
+func (c *Client) shouldRetrySafetyBlock(model *genai.GenerativeModel, err error) bool {
+	if !c.safetyBlockRetry || len(model.SafetySettings) > 0 {
+		return false
+	}
+	var blocked *genai.BlockedError
+	if !errors.As(err, &blocked) {
+		return false
+	}
+	if blocked.Candidate != nil && blocked.Candidate.FinishReason == genai.FinishReasonSafety {
+		return true
+	}
+	return blocked.PromptFeedback != nil && blocked.PromptFeedback.BlockReason == genai.BlockReasonSafety
+}
+
+// withRequestTimeout возвращает контекст запроса: если у ctx нет дедлайна, ограничивает его requestTimeout.
+func (c *Client) withRequestTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, hasDeadline := ctx.Deadline(); hasDeadline || c.requestTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, c.requestTimeout)
+}
+
+// wrapTimeoutError помечает ошибку как ErrTimeout, если запрос был прерван по дедлайну контекста.
+func wrapTimeoutError(ctx context.Context, err error) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %v", ErrTimeout, err)
+	}
+	return err
+}
+
+// wrapRateLimitError помечает ошибку как ErrRateLimited, если Gemini ответил 429 / RESOURCE_EXHAUSTED.
+func wrapRateLimitError(err error) error {
+	if errors.Is(err, ErrTimeout) {
+		return err
+	}
+	msg := err.Error()
+	if strings.Contains(msg, "429") || strings.Contains(msg, "RESOURCE_EXHAUSTED") || strings.Contains(msg, "ResourceExhausted") {
+		return fmt.Errorf("%w: %v", ErrRateLimited, err)
+	}
+	return err
+}
+
 // truncateString обрезает строку до maxLen, стараясь не рвать слова.
 func truncateString(s string, maxLen int) string {
 	if len(s) <= maxLen {
@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"fmt"
+	"log"
+	"os"
+)
+
+// backupSuffix - суффикс резервной копии предыдущей версии файла.
+const backupSuffix = ".bak"
+
+// WriteFileAtomic записывает файл атомарно: данные пишутся во временный файл, который затем
+// переименовывается в path, поэтому при сбое во время записи на диске остается прежняя версия.
+// Если keepBackup включен, предыдущая версия сохраняется как path.bak (см. ReadFileWithBackup).
+func WriteFileAtomic(path string, data []byte, keepBackup bool) error {
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("ошибка записи временного файла %s: %w", tmpPath, err)
+	}
+
+	if keepBackup {
+		// Переименование вместо копирования: если процесс упадет до следующего шага,
+		// основного файла не будет и ReadFileWithBackup прочитает резервную копию.
+		if err := os.Rename(path, path+backupSuffix); err != nil && !os.IsNotExist(err) {
+			log.Printf("[Storage WARN] Не удалось создать резервную копию %s: %v", path, err)
+		}
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("ошибка переименования %s -> %s: %w", tmpPath, path, err)
+	}
+	return nil
+}
+
+// ReadFileWithBackup читает файл и разбирает его функцией parse. Если основной файл отсутствует
+// или не разбирается, используется резервная копия path.bak (если она есть).
+// Возвращает os.ErrNotExist (через errors.Is), если нет ни файла, ни копии.
+func ReadFileWithBackup(path string, parse func(data []byte) error) (usedBackup bool, err error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		if err = parse(data); err == nil {
+			return false, nil
+		}
+		err = fmt.Errorf("ошибка разбора %s: %w", path, err)
+	} else if !os.IsNotExist(err) {
+		err = fmt.Errorf("ошибка чтения %s: %w", path, err)
+	}
+
+	backupPath := path + backupSuffix
+	backupData, backupErr := os.ReadFile(backupPath)
+	if backupErr != nil {
+		// Резервной копии нет - возвращаем исходную ошибку
+		return false, err
+	}
+	if parseErr := parse(backupData); parseErr != nil {
+		return false, fmt.Errorf("%v; резервная копия %s также повреждена: %w", err, backupPath, parseErr)
+	}
+	log.Printf("[Storage WARN] %v. Данные восстановлены из резервной копии %s.", err, backupPath)
+	return true, nil
+}
+
+// fileOrBackupExists проверяет, есть ли на диске файл или его резервная копия.
+func fileOrBackupExists(path string) bool {
+	for _, p := range []string{path, path + backupSuffix} {
+		if _, err := os.Stat(p); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// removeBackup удаляет резервную копию файла. Используется после явного удаления данных
+// пользователем, чтобы удаленные сообщения не вернулись из резервной копии.
+func removeBackup(path string) {
+	if err := os.Remove(path + backupSuffix); err != nil && !os.IsNotExist(err) {
+		log.Printf("[Storage WARN] Не удалось удалить резервную копию %s: %v", path+backupSuffix, err)
+	}
+}
@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// embeddingBreaker - простой предохранитель (circuit breaker) для запросов эмбеддингов.
+// После threshold подряд неудачных запросов он "размыкается" на cooldown: в это время
+// запросы эмбеддингов не выполняются. По истечении cooldown пропускается одна пробная
+// попытка (полуоткрытое состояние): успех замыкает предохранитель, ошибка размыкает снова.
+type embeddingBreaker struct {
+	mu                  sync.Mutex
+	threshold           int
+	cooldown            time.Duration
+	consecutiveFailures int
+	openUntil           time.Time
+	halfOpenProbe       bool // Пробная попытка уже выдана и ждет результата
+	skipLogged          bool // Пропуск в текущем размыкании уже залогирован
+}
+
+// newEmbeddingBreaker создает предохранитель. threshold <= 0 отключает его.
+func newEmbeddingBreaker(threshold int, cooldown time.Duration) *embeddingBreaker {
+	return &embeddingBreaker{threshold: threshold, cooldown: cooldown}
+}
+
+// Allow сообщает, можно ли сейчас выполнять запрос эмбеддинга.
+func (eb *embeddingBreaker) Allow() bool {
+	if eb == nil || eb.threshold <= 0 {
+		return true
+	}
+	eb.mu.Lock()
+	defer eb.mu.Unlock()
+
+	if eb.openUntil.IsZero() {
+		return true
+	}
+	if time.Now().Before(eb.openUntil) || eb.halfOpenProbe {
+		if !eb.skipLogged {
+			log.Printf("[Embedding Breaker] Предохранитель разомкнут до %s, запись в долговременную память приостановлена.", eb.openUntil.Format("15:04:05"))
+			eb.skipLogged = true
+		}
+		return false
+	}
+	// Cooldown истек: пропускаем одну пробную попытку
+	eb.halfOpenProbe = true
+	log.Printf("[Embedding Breaker] Cooldown истек, пробный запрос эмбеддинга...")
+	return true
+}
+
+// RecordSuccess замыкает предохранитель после успешного запроса.
+func (eb *embeddingBreaker) RecordSuccess() {
+	if eb == nil || eb.threshold <= 0 {
+		return
+	}
+	eb.mu.Lock()
+	defer eb.mu.Unlock()
+
+	if !eb.openUntil.IsZero() {
+		log.Printf("[Embedding Breaker] Эмбеддинги снова доступны, запись в долговременную память возобновлена.")
+	}
+	eb.consecutiveFailures = 0
+	eb.openUntil = time.Time{}
+	eb.halfOpenProbe = false
+	eb.skipLogged = false
+}
+
+// RecordFailure учитывает неудачный запрос и при необходимости размыкает предохранитель.
+func (eb *embeddingBreaker) RecordFailure() {
+	if eb == nil || eb.threshold <= 0 {
+		return
+	}
+	eb.mu.Lock()
+	defer eb.mu.Unlock()
+
+	eb.consecutiveFailures++
+	if eb.halfOpenProbe || eb.consecutiveFailures >= eb.threshold {
+		eb.openUntil = time.Now().Add(eb.cooldown)
+		eb.halfOpenProbe = false
+		eb.skipLogged = false
+		log.Printf("[Embedding Breaker WARN] %d ошибок эмбеддингов подряд. Предохранитель разомкнут на %s.", eb.consecutiveFailures, eb.cooldown)
+	}
+}
@@ -3,9 +3,11 @@ package storage
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
+	"math/rand"
 	"os"
 	"path/filepath"
 	"strings"
@@ -14,8 +16,8 @@ import (
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	// Добавляем импорт types
+	"github.com/Henry-Case-dev/rofloslav/internal/config"
 	"github.com/Henry-Case-dev/rofloslav/internal/types"
-	// config не нужен здесь, т.к. contextWindow передается при создании
 )
 
 // --- LocalStorage ---
@@ -23,19 +25,26 @@ import (
 // LocalStorage реализует HistoryStorage с использованием локальной файловой системы.
 type LocalStorage struct {
 	messages      map[int64][]*tgbotapi.Message
-	contextWindow int
+	contextWindow int    // Максимум сообщений на чат в памяти, более старые вытесняются (см. trimToWindow)
 	dataDir       string // Путь к директории для сохранения файлов
 	mutex         sync.RWMutex
+
+	loadConcurrency int // Сколько историй загружается параллельно при старте
+	loadMaxMessages int // Сколько последних сообщений чата загружается при старте (0 - все)
+
+	keepBackup bool // Хранить предыдущую версию файла истории как .bak (LOCAL_STORAGE_BACKUP)
+	gzip       bool // Сохранять историю сжатой (chat_<id>.json.gz, FILE_STORAGE_GZIP)
+	storeRaw   bool // Сохранять полный JSON сообщений (STORE_RAW_UPDATES)
+
+	// Полный JSON сообщений из памяти (STORE_RAW_UPDATES): chatID -> messageID -> JSON.
+	// Хранится отдельно, потому что в messages лежат уже очищенные сообщения
+	raw map[int64]map[int]json.RawMessage
 }
 
 // NewLocalStorage создает новый экземпляр LocalStorage.
-func NewLocalStorage(contextWindow int) (*LocalStorage, error) {
-	// Определяем директорию для данных. В Docker это будет /data
-	dataDir := os.Getenv("DATA_DIR")
-	if dataDir == "" {
-		dataDir = "data" // Используем локальную папку data, если переменная не задана
-	}
-
+// Размер окна контекста и параметры загрузки историй при старте берутся из конфига.
+func NewLocalStorage(cfg *config.Config) (*LocalStorage, error) {
+	dataDir := DataDir()
 	log.Printf("[LocalStorage] Инициализация с dataDir: %s", dataDir)
 
 	// Убедимся, что директория существует
@@ -44,10 +53,22 @@ func NewLocalStorage(contextWindow int) (*LocalStorage, error) {
 	}
 
 	ls := &LocalStorage{
-		messages:      make(map[int64][]*tgbotapi.Message),
-		contextWindow: contextWindow,
-		dataDir:       dataDir,
-		mutex:         sync.RWMutex{},
+		messages:        make(map[int64][]*tgbotapi.Message),
+		contextWindow:   cfg.ContextWindow,
+		dataDir:         dataDir,
+		mutex:           sync.RWMutex{},
+		loadConcurrency: cfg.StartupHistoryLoadConcurrency,
+		loadMaxMessages: cfg.StartupHistoryMaxMessages,
+		keepBackup:      cfg.LocalStorageBackup,
+		gzip:            cfg.FileStorageGzip,
+		storeRaw:        cfg.StoreRawUpdates,
+		raw:             make(map[int64]map[int]json.RawMessage),
+	}
+
+	// При ленивой загрузке история чата читается при первом сообщении после старта (см. bot.ensureHistoryLoaded)
+	if cfg.LazyHistoryLoad {
+		log.Printf("[LocalStorage] LAZY_HISTORY_LOAD включен, истории из %s будут загружаться по мере обращения.", dataDir)
+		return ls, nil
 	}
 
 	// Загружаем существующие истории при старте
@@ -63,6 +84,14 @@ func NewLocalStorage(contextWindow int) (*LocalStorage, error) {
 	return ls, nil
 }
 
+// DataDir возвращает директорию для файлов данных (DATA_DIR). В Docker это будет /data.
+func DataDir() string {
+	if dataDir := os.Getenv("DATA_DIR"); dataDir != "" {
+		return dataDir
+	}
+	return "data" // Используем локальную папку data, если переменная не задана
+}
+
 // ensureDataDir проверяет и при необходимости создает директорию для данных.
 func ensureDataDir(dirPath string) error {
 	err := os.MkdirAll(dirPath, 0755) // 0755 - стандартные права доступа
@@ -74,19 +103,127 @@ func ensureDataDir(dirPath string) error {
 	return nil
 }
 
+// CheckDataDir проверяет, что в директорию данных можно писать (для CONFIG_CHECK), не создавая ее:
+// временный файл удаляется сразу. Отсутствующая директория не ошибка - она будет создана при запуске,
+// если доступна на запись ближайшая существующая родительская директория.
+func CheckDataDir() (string, error) {
+	dir := DataDir()
+	for {
+		info, err := os.Stat(dir)
+		if err == nil {
+			if !info.IsDir() {
+				return "", fmt.Errorf("%s не является директорией", dir)
+			}
+			break
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", err
+		}
+		dir = parent
+	}
+
+	probe, err := os.CreateTemp(dir, ".config_check_*")
+	if err != nil {
+		return "", fmt.Errorf("нет прав на запись в %s: %w", dir, err)
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+	if dir != DataDir() {
+		return fmt.Sprintf("%s (будет создана в %s)", DataDir(), dir), nil
+	}
+	return dir, nil
+}
+
 // --- Реализация интерфейса HistoryStorage ---
 
-// AddMessage добавляет сообщение в память и обрезает историю.
+// AddMessage добавляет сообщение в память (очищенным sanitizeMessage) и обрезает историю.
 func (ls *LocalStorage) AddMessage(chatID int64, message *tgbotapi.Message) {
+	ls.AddMessageWithRaw(chatID, message, nil)
+}
+
+// AddMessageWithRaw добавляет сообщение вместе с его полным JSON (сохраняется только с STORE_RAW_UPDATES).
+func (ls *LocalStorage) AddMessageWithRaw(chatID int64, message *tgbotapi.Message, raw json.RawMessage) {
+	messageID := message.MessageID
+	message = sanitizeMessage(message)
 	ls.mutex.Lock()
 	defer ls.mutex.Unlock()
 
 	if _, exists := ls.messages[chatID]; !exists {
 		ls.messages[chatID] = make([]*tgbotapi.Message, 0)
 	}
-	ls.messages[chatID] = append(ls.messages[chatID], message)
-	if len(ls.messages[chatID]) > ls.contextWindow {
-		ls.messages[chatID] = ls.messages[chatID][len(ls.messages[chatID])-ls.contextWindow:]
+	ls.messages[chatID] = ls.trimToWindow(append(ls.messages[chatID], message))
+	if ls.storeRaw && len(raw) > 0 {
+		if ls.raw[chatID] == nil {
+			ls.raw[chatID] = make(map[int]json.RawMessage)
+		}
+		ls.raw[chatID][messageID] = raw
+	}
+	ls.pruneRaw(chatID)
+}
+
+// pruneRaw удаляет полный JSON сообщений, которых больше нет в памяти (вытеснены или удалены).
+// Вызывается под ls.mutex.
+func (ls *LocalStorage) pruneRaw(chatID int64) {
+	rawByID := ls.raw[chatID]
+	if len(rawByID) == 0 {
+		return
+	}
+	present := make(map[int]bool, len(ls.messages[chatID]))
+	for _, msg := range ls.messages[chatID] {
+		present[msg.MessageID] = true
+	}
+	for id := range rawByID {
+		if !present[id] {
+			delete(rawByID, id)
+		}
+	}
+	if len(rawByID) == 0 {
+		delete(ls.raw, chatID)
+	}
+}
+
+// trimToWindow оставляет не более contextWindow последних сообщений, вытесняя самые старые.
+// Сообщения копируются в новый срез: простой срез s[n:] держал бы старые сообщения
+// в общем массиве, и они не освобождались бы сборщиком мусора. Так на чат в памяти
+// приходится не больше contextWindow сообщений (CONTEXT_WINDOW).
+func (ls *LocalStorage) trimToWindow(messages []*tgbotapi.Message) []*tgbotapi.Message {
+	if ls.contextWindow <= 0 || len(messages) <= ls.contextWindow {
+		return messages
+	}
+	trimmed := make([]*tgbotapi.Message, ls.contextWindow, ls.contextWindow+1)
+	copy(trimmed, messages[len(messages)-ls.contextWindow:])
+	return trimmed
+}
+
+// UpdateMessage заменяет сообщение с тем же MessageID в памяти и перезаписывает файл истории,
+// чтобы правка не потерялась при перезапуске до следующего сохранения.
+// Если сообщение уже вытеснено из окна контекста, изменение игнорируется.
+func (ls *LocalStorage) UpdateMessage(chatID int64, message *tgbotapi.Message) {
+	message = sanitizeMessage(message)
+	ls.mutex.Lock()
+	messages := ls.messages[chatID]
+	found := false
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].MessageID == message.MessageID {
+			messages[i] = message
+			// Полный JSON относится к тексту до правки
+			delete(ls.raw[chatID], message.MessageID)
+			found = true
+			break
+		}
+	}
+	ls.mutex.Unlock()
+
+	if !found {
+		log.Printf("[LocalStorage DEBUG] Чат %d: Отредактированное сообщение %d не найдено в памяти, пропускаем.", chatID, message.MessageID)
+		return
+	}
+	if err := ls.SaveChatHistory(chatID); err != nil {
+		log.Printf("[LocalStorage ERROR] Чат %d: Не удалось сохранить историю после правки сообщения %d: %v", chatID, message.MessageID, err)
 	}
 }
 
@@ -98,10 +235,11 @@ func (ls *LocalStorage) AddMessagesToContext(chatID int64, messages []*tgbotapi.
 	if _, exists := ls.messages[chatID]; !exists {
 		ls.messages[chatID] = make([]*tgbotapi.Message, 0)
 	}
-	ls.messages[chatID] = append(ls.messages[chatID], messages...)
-	if len(ls.messages[chatID]) > ls.contextWindow {
-		ls.messages[chatID] = ls.messages[chatID][len(ls.messages[chatID])-ls.contextWindow:]
+	for _, message := range messages {
+		ls.messages[chatID] = append(ls.messages[chatID], sanitizeMessage(message))
 	}
+	ls.messages[chatID] = ls.trimToWindow(ls.messages[chatID])
+	ls.pruneRaw(chatID)
 }
 
 // GetMessages возвращает сообщения из памяти.
@@ -136,82 +274,241 @@ func (ls *LocalStorage) GetMessagesSince(chatID int64, since time.Time) []*tgbot
 func (ls *LocalStorage) ClearChatHistory(chatID int64) {
 	ls.mutex.Lock()
 	delete(ls.messages, chatID)
+	delete(ls.raw, chatID)
 	ls.mutex.Unlock() // Разблокируем перед удалением файла
 
 	filePath := ls.getFilePath(chatID)
-	err := os.Remove(filePath)
-	if err != nil && !os.IsNotExist(err) {
+	removed, err := ls.removeHistoryFiles(chatID)
+	if err != nil {
 		log.Printf("[LocalStorage WARN] Чат %d: Не удалось удалить файл истории %s: %v", chatID, filePath, err)
-	} else if err == nil {
+	} else if removed {
 		log.Printf("[LocalStorage] Чат %d: История в памяти очищена и файл %s удален.", chatID, filePath)
 	} else {
 		log.Printf("[LocalStorage] Чат %d: История в памяти очищена (файл %s не найден).", chatID, filePath)
 	}
 }
 
+// ClearUserHistory удаляет из памяти все сообщения пользователя и перезаписывает файл истории.
+func (ls *LocalStorage) ClearUserHistory(chatID int64, userID int64) error {
+	ls.mutex.Lock()
+	messages := ls.messages[chatID]
+	kept := make([]*tgbotapi.Message, 0, len(messages))
+	for _, msg := range messages {
+		if msg.From == nil || msg.From.ID != userID {
+			kept = append(kept, msg)
+		}
+	}
+	removed := len(messages) - len(kept)
+	if removed > 0 {
+		ls.messages[chatID] = kept
+		ls.pruneRaw(chatID)
+	}
+	ls.mutex.Unlock()
+
+	if removed == 0 {
+		return nil
+	}
+	log.Printf("[LocalStorage] Чат %d: Удалено %d сообщений пользователя %d.", chatID, removed, userID)
+	filePath := ls.getFilePath(chatID)
+	if len(kept) == 0 {
+		// SaveChatHistory не пишет пустую историю, поэтому удаляем файл сами
+		if _, err := ls.removeHistoryFiles(chatID); err != nil {
+			return fmt.Errorf("ошибка удаления файла истории: %w", err)
+		}
+		return nil
+	}
+	err := ls.SaveChatHistory(chatID)
+	removeBackup(filePath) // В резервной копии остались удаленные сообщения
+	return err
+}
+
+// DeleteMessage удаляет сообщение из памяти и перезаписывает файл истории,
+// чтобы удаленное сообщение не вернулось при следующей загрузке.
+func (ls *LocalStorage) DeleteMessage(chatID int64, messageID int) error {
+	ls.mutex.Lock()
+	messages := ls.messages[chatID]
+	found := false
+	for i, msg := range messages {
+		if msg.MessageID == messageID {
+			ls.messages[chatID] = append(messages[:i:i], messages[i+1:]...)
+			ls.pruneRaw(chatID)
+			found = true
+			break
+		}
+	}
+	ls.mutex.Unlock()
+
+	if !found {
+		return nil
+	}
+	log.Printf("[LocalStorage] Чат %d: Сообщение %d удалено из памяти.", chatID, messageID)
+	err := ls.SaveChatHistory(chatID)
+	removeBackup(ls.getFilePath(chatID)) // В резервной копии осталось удаленное сообщение
+	return err
+}
+
+// GetRawMessage возвращает полный JSON сообщения, полученный при его добавлении (до очистки текста).
+// Есть только у сообщений в окне контекста, сохраненных с STORE_RAW_UPDATES.
+func (ls *LocalStorage) GetRawMessage(chatID int64, messageID int) ([]byte, error) {
+	if !ls.storeRaw {
+		return nil, ErrNotSupported
+	}
+	ls.mutex.RLock()
+	defer ls.mutex.RUnlock()
+	return ls.raw[chatID][messageID], nil
+}
+
+// BackendName возвращает название реализации хранилища.
+func (ls *LocalStorage) BackendName() string {
+	return "local"
+}
+
+// SupportsVectorSearch - локальное хранилище ищет только по вхождению текста.
+func (ls *LocalStorage) SupportsVectorSearch() bool {
+	return false
+}
+
+// GetCollectionInfo не поддерживается: у локального хранилища нет векторной коллекции.
+func (ls *LocalStorage) GetCollectionInfo() (*CollectionStats, error) {
+	return nil, ErrNotSupported
+}
+
 // --- Функции Load/Save для файлов ---
 
+// getFilePath возвращает путь к файлу истории чата в текущем формате (.json или .json.gz).
 func (ls *LocalStorage) getFilePath(chatID int64) string {
-	return filepath.Join(ls.dataDir, fmt.Sprintf("chat_%d.json", chatID))
+	filePath := filepath.Join(ls.dataDir, fmt.Sprintf("chat_%d.json", chatID))
+	if ls.gzip {
+		return filePath + gzipFileSuffix
+	}
+	return filePath
+}
+
+// otherFormatFilePath возвращает путь к файлу истории в другом формате - он остается на диске,
+// если FILE_STORAGE_GZIP переключили, и читается до первого сохранения в новом формате.
+func (ls *LocalStorage) otherFormatFilePath(chatID int64) string {
+	filePath := filepath.Join(ls.dataDir, fmt.Sprintf("chat_%d.json", chatID))
+	if ls.gzip {
+		return filePath
+	}
+	return filePath + gzipFileSuffix
+}
+
+// removeHistoryFiles удаляет файлы истории чата в обоих форматах вместе с резервными копиями.
+// Возвращает true, если был удален хотя бы один файл истории.
+func (ls *LocalStorage) removeHistoryFiles(chatID int64) (bool, error) {
+	removed := false
+	var firstErr error
+	for _, filePath := range []string{ls.getFilePath(chatID), ls.otherFormatFilePath(chatID)} {
+		removeBackup(filePath)
+		err := os.Remove(filePath)
+		if err == nil {
+			removed = true
+		} else if !os.IsNotExist(err) && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return removed, firstErr
 }
 
 // LoadChatHistory загружает историю из файла.
 func (ls *LocalStorage) LoadChatHistory(chatID int64) ([]*tgbotapi.Message, error) {
+	return ls.loadChatHistory(chatID, 0)
+}
+
+// loadChatHistory загружает историю из файла, оставляя не более maxMessages последних сообщений (0 - все).
+func (ls *LocalStorage) loadChatHistory(chatID int64, maxMessages int) ([]*tgbotapi.Message, error) {
 	filePath := ls.getFilePath(chatID)
+	if !fileOrBackupExists(filePath) && fileOrBackupExists(ls.otherFormatFilePath(chatID)) {
+		// История сохранена в другом формате (FILE_STORAGE_GZIP переключили) - при следующем сохранении она будет сконвертирована
+		filePath = ls.otherFormatFilePath(chatID)
+	}
 	// log.Printf("[LocalStorage] Загружаю историю для чата %d из файла: %s", chatID, filePath)
 
-	data, err := ioutil.ReadFile(filePath)
+	// Пустой файл или null - история чата пуста
+	var storedMessages []*StoredMessage
+	empty := false
+	usedBackup, err := ReadFileWithBackup(filePath, func(data []byte) error {
+		storedMessages = nil
+		data, err := decompressIfGzip(data)
+		if err != nil {
+			return err
+		}
+		empty = len(data) == 0 || string(data) == "null"
+		if empty {
+			return nil
+		}
+		return json.Unmarshal(data, &storedMessages)
+	})
 	if err != nil {
-		if os.IsNotExist(err) {
+		if errors.Is(err, os.ErrNotExist) {
 			// log.Printf("[LocalStorage] Файл истории %s не найден.", filePath)
 			return nil, nil // Не ошибка, просто нет истории
 		}
-		log.Printf("[LocalStorage ERROR] Ошибка чтения файла %s: %v", filePath, err)
-		return nil, fmt.Errorf("ошибка чтения файла истории: %w", err)
+		log.Printf("[LocalStorage ERROR] Ошибка загрузки истории из файла %s: %v", filePath, err)
+		ls.quarantineCorruptedFile(filePath)
+		return nil, fmt.Errorf("ошибка загрузки истории: %w", err)
+	}
+	if usedBackup {
+		// Поврежденный основной файл не должен попасть в резервную копию при следующем сохранении
+		ls.quarantineCorruptedFile(filePath)
 	}
 
-	if len(data) == 0 || string(data) == "null" {
+	if empty {
 		// log.Printf("[LocalStorage] Файл %s пуст или содержит null.", filePath)
 		ls.ClearChatHistory(chatID) // Очищаем память, если файл пуст
 		return []*tgbotapi.Message{}, nil
 	}
 
-	var storedMessages []*StoredMessage
-	if err := json.Unmarshal(data, &storedMessages); err != nil {
-		log.Printf("[LocalStorage ERROR] Ошибка десериализации JSON из файла %s: %v", filePath, err)
-		// Попытаемся переименовать поврежденный файл
-		backupPath := filePath + ".corrupted." + time.Now().Format("20060102150405")
-		if renameErr := os.Rename(filePath, backupPath); renameErr == nil {
-			log.Printf("[LocalStorage INFO] Поврежденный файл %s переименован в %s", filePath, backupPath)
-		} else {
-			log.Printf("[LocalStorage ERROR] Не удалось переименовать поврежденный файл %s: %v", filePath, renameErr)
-		}
-		return nil, fmt.Errorf("ошибка десериализации истории: %w", err)
-	}
-
 	var messages []*tgbotapi.Message
+	rawByID := make(map[int]json.RawMessage)
 	for _, stored := range storedMessages {
+		// Источник истины - сохраненные поля; полный JSON (STORE_RAW_UPDATES) только показывается командой /raw
 		apiMsg := ConvertToAPIMessage(stored) // Используем конвертер из storage.go
+		if apiMsg != nil && len(stored.Raw) > 0 {
+			rawByID[apiMsg.MessageID] = stored.Raw
+		}
 		if apiMsg != nil {
 			messages = append(messages, apiMsg)
 		} else {
 			log.Printf("[LocalStorage WARN] Чат %d: Не удалось конвертировать StoredMessage ID %d из файла %s", chatID, stored.MessageID, filePath)
 		}
 	}
+	if maxMessages > 0 && len(messages) > maxMessages {
+		messages = messages[len(messages)-maxMessages:]
+	}
 	log.Printf("[LocalStorage OK] Чат %d: Успешно загружено %d сообщений из %s.", chatID, len(messages), filePath)
 
-	// Обновляем кеш в памяти
+	// Обновляем кеш в памяти (файл может быть больше окна контекста)
 	ls.mutex.Lock()
-	ls.messages[chatID] = messages
+	ls.messages[chatID] = ls.trimToWindow(messages)
+	if ls.storeRaw {
+		ls.raw[chatID] = rawByID
+		ls.pruneRaw(chatID)
+	}
 	ls.mutex.Unlock()
 
 	return messages, nil
 }
 
+// quarantineCorruptedFile переименовывает поврежденный файл истории, чтобы сохранить его для разбора.
+func (ls *LocalStorage) quarantineCorruptedFile(filePath string) {
+	backupPath := filePath + ".corrupted." + time.Now().Format("20060102150405")
+	if renameErr := os.Rename(filePath, backupPath); renameErr == nil {
+		log.Printf("[LocalStorage INFO] Поврежденный файл %s переименован в %s", filePath, backupPath)
+	} else if !os.IsNotExist(renameErr) {
+		log.Printf("[LocalStorage ERROR] Не удалось переименовать поврежденный файл %s: %v", filePath, renameErr)
+	}
+}
+
 // SaveChatHistory сохраняет историю чата (из памяти) в файл.
 func (ls *LocalStorage) SaveChatHistory(chatID int64) error {
 	ls.mutex.RLock()
 	messages, exists := ls.messages[chatID]
+	rawByID := make(map[int]json.RawMessage, len(ls.raw[chatID]))
+	for id, data := range ls.raw[chatID] {
+		rawByID[id] = data
+	}
 	ls.mutex.RUnlock()
 
 	if !exists || len(messages) == 0 {
@@ -228,6 +525,9 @@ func (ls *LocalStorage) SaveChatHistory(chatID int64) error {
 	var storedMessages []*StoredMessage
 	for _, msg := range messages {
 		stored := ConvertToStoredMessage(msg) // Используем конвертер из storage.go
+		if stored != nil {
+			stored.Raw = rawByID[msg.MessageID]
+		}
 		if stored != nil {
 			storedMessages = append(storedMessages, stored)
 		}
@@ -239,21 +539,23 @@ func (ls *LocalStorage) SaveChatHistory(chatID int64) error {
 		return fmt.Errorf("ошибка маршалинга истории: %w", err)
 	}
 
-	// Атомарная запись: сначала пишем во временный файл, потом переименовываем
-	tempFilePath := filePath + ".tmp"
-	err = ioutil.WriteFile(tempFilePath, data, 0644) // 0644 - стандартные права
-	if err != nil {
-		log.Printf("[LocalStorage ERROR] Чат %d: Ошибка записи во временный файл %s: %v", chatID, tempFilePath, err)
-		return fmt.Errorf("ошибка записи временного файла истории: %w", err)
+	if ls.gzip {
+		if data, err = compressGzip(data); err != nil {
+			log.Printf("[LocalStorage ERROR] Чат %d: Ошибка сжатия истории: %v", chatID, err)
+			return fmt.Errorf("ошибка сжатия истории: %w", err)
+		}
 	}
 
-	// Переименовываем временный файл в основной
-	err = os.Rename(tempFilePath, filePath)
-	if err != nil {
-		log.Printf("[LocalStorage ERROR] Чат %d: Ошибка переименования файла %s -> %s: %v", chatID, tempFilePath, filePath, err)
-		// Попытаемся удалить временный файл, если переименование не удалось
-		_ = os.Remove(tempFilePath)
-		return fmt.Errorf("ошибка переименования файла истории: %w", err)
+	// Атомарная запись с резервной копией предыдущей версии (LOCAL_STORAGE_BACKUP)
+	if err := WriteFileAtomic(filePath, data, ls.keepBackup); err != nil {
+		log.Printf("[LocalStorage ERROR] Чат %d: Ошибка сохранения истории: %v", chatID, err)
+		return fmt.Errorf("ошибка сохранения файла истории: %w", err)
+	}
+	// Файл в прежнем формате больше не нужен, иначе после удаления сообщений он мог бы загрузиться снова
+	otherPath := ls.otherFormatFilePath(chatID)
+	removeBackup(otherPath)
+	if err := os.Remove(otherPath); err != nil && !os.IsNotExist(err) {
+		log.Printf("[LocalStorage WARN] Чат %d: Не удалось удалить файл истории в прежнем формате %s: %v", chatID, otherPath, err)
 	}
 
 	// log.Printf("[LocalStorage OK] Чат %d: История (%d сообщ.) записана в %s.", chatID, len(storedMessages), filePath)
@@ -299,6 +601,8 @@ func (ls *LocalStorage) SaveAllChatHistories() error {
 }
 
 // loadAllChatHistories загружает все истории из файлов в директории dataDir.
+// Одновременно загружается не больше loadConcurrency историй (STARTUP_HISTORY_LOAD_CONCURRENCY),
+// из каждой берутся только loadMaxMessages последних сообщений (STARTUP_HISTORY_MAX_MESSAGES).
 func (ls *LocalStorage) loadAllChatHistories() error {
 	files, err := ioutil.ReadDir(ls.dataDir)
 	if err != nil {
@@ -306,27 +610,64 @@ func (ls *LocalStorage) loadAllChatHistories() error {
 		return fmt.Errorf("ошибка чтения директории истории: %w", err)
 	}
 
+	concurrency := ls.loadConcurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	semaphore := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	var countMutex sync.Mutex
 	loadedCount := 0
+
+	existing := make(map[string]bool, len(files))
 	for _, file := range files {
-		if !file.IsDir() && filepath.Ext(file.Name()) == ".json" && strings.HasPrefix(file.Name(), "chat_") {
+		existing[file.Name()] = true
+	}
+
+	seen := make(map[int64]bool)
+	for _, file := range files {
+		name := file.Name()
+		if strings.HasSuffix(name, backupSuffix) {
+			// Резервная копия без основного файла остается после сбоя во время сохранения
+			name = strings.TrimSuffix(name, backupSuffix)
+			if existing[name] {
+				continue
+			}
+		}
+		name = strings.TrimSuffix(name, gzipFileSuffix) // chat_<id>.json.gz (FILE_STORAGE_GZIP)
+		if !file.IsDir() && filepath.Ext(name) == ".json" && strings.HasPrefix(name, "chat_") {
 			// Пытаемся извлечь chatID из имени файла
 			var chatID int64
-			baseName := strings.TrimSuffix(file.Name(), ".json")
+			baseName := strings.TrimSuffix(name, ".json")
 			baseName = strings.TrimPrefix(baseName, "chat_")
-			if _, err := fmt.Sscan(baseName, &chatID); err == nil && chatID != 0 {
-				// log.Printf("[LocalStorage LoadAll] Найден файл: %s, пытаюсь загрузить для chatID: %d", file.Name(), chatID)
-				_, loadErr := ls.LoadChatHistory(chatID) // LoadChatHistory обновит кеш
-				if loadErr != nil {
-					log.Printf("[LocalStorage LoadAll WARN] Ошибка загрузки истории для чата %d из файла %s: %v", chatID, file.Name(), loadErr)
-					// Не прерываем цикл, продолжаем загружать остальные
-				} else {
-					loadedCount++
-				}
-			} else {
+			if _, err := fmt.Sscan(baseName, &chatID); err != nil || chatID == 0 {
 				log.Printf("[LocalStorage LoadAll WARN] Не удалось извлечь chatID из имени файла: %s", file.Name())
+				continue
+			}
+			if seen[chatID] {
+				continue // История чата есть в обоих форматах, loadChatHistory сам выберет файл
 			}
+			seen[chatID] = true
+
+			wg.Add(1)
+			go func(chatID int64, fileName string) {
+				defer wg.Done()
+				semaphore <- struct{}{}
+				defer func() { <-semaphore }()
+
+				// loadChatHistory обновит кеш
+				if _, loadErr := ls.loadChatHistory(chatID, ls.loadMaxMessages); loadErr != nil {
+					log.Printf("[LocalStorage LoadAll WARN] Ошибка загрузки истории для чата %d из файла %s: %v", chatID, fileName, loadErr)
+					// Не прерываем загрузку, продолжаем загружать остальные
+					return
+				}
+				countMutex.Lock()
+				loadedCount++
+				countMutex.Unlock()
+			}(chatID, file.Name())
 		}
 	}
+	wg.Wait()
 	log.Printf("[LocalStorage LoadAll] Завершено сканирование директории '%s'. Загружено историй: %d.", ls.dataDir, loadedCount)
 	return nil
 }
@@ -339,6 +680,52 @@ func (ls *LocalStorage) ImportMessagesFromJSONFile(chatID int64, filePath string
 	return 0, 0, nil // Возвращаем 0 импортированных, 0 пропущенных
 }
 
+// GetRandomMessage выбирает случайное сообщение из истории чата в памяти.
+func (ls *LocalStorage) GetRandomMessage(chatID int64) (*types.Message, error) {
+	ls.mutex.RLock()
+	var candidates []*tgbotapi.Message
+	for _, msg := range ls.messages[chatID] {
+		// MessageID 0 - служебные сообщения (саммари)
+		if msg.MessageID != 0 && (msg.From == nil || !msg.From.IsBot) && isQuotableText(MessageText(msg)) {
+			candidates = append(candidates, msg)
+		}
+	}
+	ls.mutex.RUnlock()
+
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+	msg := candidates[rand.Intn(len(candidates))]
+	result := &types.Message{
+		ID:            int64(msg.MessageID),
+		ChatID:        chatID,
+		Text:          MessageText(msg),
+		Timestamp:     msg.Date,
+		ForwardedFrom: ForwardSource(msg),
+	}
+	if msg.From != nil {
+		result.UserID = msg.From.ID
+		result.UserName = msg.From.UserName
+		result.FirstName = msg.From.FirstName
+	}
+	return result, nil
+}
+
+// GetChatStats считает статистику чата за период по сообщениям в памяти.
+func (ls *LocalStorage) GetChatStats(chatID int64, since time.Time) (*ChatStats, error) {
+	ls.mutex.RLock()
+	defer ls.mutex.RUnlock()
+
+	builder := newChatStatsBuilder(since)
+	for _, msg := range ls.messages[chatID] {
+		if msg.From == nil || msg.MessageID == 0 {
+			continue
+		}
+		builder.add(msg.From.ID, msg.From.UserName, msg.From.FirstName, msg.From.IsBot, msg.Date)
+	}
+	return builder.result(), nil
+}
+
 // FindRelevantMessages - Заглушка для LocalStorage.
 // Всегда возвращает пустой срез и nil ошибку.
 // Используем types.Message
@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func newTestMessages(from, count int) []*tgbotapi.Message {
+	messages := make([]*tgbotapi.Message, 0, count)
+	for id := from; id < from+count; id++ {
+		messages = append(messages, &tgbotapi.Message{MessageID: id, Text: "сообщение"})
+	}
+	return messages
+}
+
+func assertNewestKept(t *testing.T, got []*tgbotapi.Message, window, total int) {
+	t.Helper()
+	if len(got) != window {
+		t.Fatalf("len(GetMessages) = %d, want %d", len(got), window)
+	}
+	for i, msg := range got {
+		if want := total - window + 1 + i; msg.MessageID != want {
+			t.Errorf("GetMessages[%d].MessageID = %d, want %d", i, msg.MessageID, want)
+		}
+	}
+}
+
+func TestLocalStorageContextWindow(t *testing.T) {
+	const (
+		chatID = int64(1)
+		window = 5
+		extra  = 3
+		total  = window + extra
+	)
+
+	t.Run("AddMessage", func(t *testing.T) {
+		ls := &LocalStorage{messages: make(map[int64][]*tgbotapi.Message), contextWindow: window}
+		for _, msg := range newTestMessages(1, total) {
+			ls.AddMessage(chatID, msg)
+		}
+		assertNewestKept(t, ls.GetMessages(chatID), window, total)
+	})
+
+	t.Run("AddMessagesToContext", func(t *testing.T) {
+		ls := &LocalStorage{messages: make(map[int64][]*tgbotapi.Message), contextWindow: window}
+		ls.AddMessagesToContext(chatID, newTestMessages(1, 2))
+		ls.AddMessagesToContext(chatID, newTestMessages(3, total-2))
+		assertNewestKept(t, ls.GetMessages(chatID), window, total)
+	})
+}
@@ -0,0 +1,42 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+// gzipFileSuffix - расширение сжатых файлов истории (FILE_STORAGE_GZIP).
+const gzipFileSuffix = ".gz"
+
+// compressGzip сжимает данные gzip.
+func compressGzip(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decompressIfGzip распаковывает данные, если они начинаются с сигнатуры gzip, иначе возвращает их как есть.
+// Формат определяется по содержимому, поэтому несжатый файл читается даже с расширением .gz и наоборот.
+func decompressIfGzip(data []byte) ([]byte, error) {
+	if len(data) < 2 || data[0] != 0x1f || data[1] != 0x8b {
+		return data, nil
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения gzip: %w", err)
+	}
+	defer reader.Close()
+	decompressed, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка распаковки gzip: %w", err)
+	}
+	return decompressed, nil
+}
@@ -0,0 +1,176 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Henry-Case-dev/rofloslav/internal/config"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// messageTextPrivacy - какие личные данные допускаются в описаниях сообщений без текста.
+// Задается один раз при старте (ConfigureMessageText), до обработки сообщений.
+var messageTextPrivacy struct {
+	storeLocation     bool // STORE_LOCATION: сохранять геопозиции и места
+	storeContactNames bool // STORE_CONTACT_NAMES: указывать имя присланного контакта
+}
+
+// ConfigureMessageText применяет настройки приватности из конфига к MessageText.
+func ConfigureMessageText(cfg *config.Config) {
+	messageTextPrivacy.storeLocation = cfg.StoreLocation
+	messageTextPrivacy.storeContactNames = cfg.StoreContactNames
+}
+
+// MessageText возвращает "эффективный" текст сообщения: Text, а для медиа с подписью - Caption.
+// Для сообщений без текста (опросы, броски, геопозиции, контакты) возвращается их текстовое описание.
+// Все хранилища должны сохранять именно его, иначе подписанные фото/видео попадают в историю пустыми.
+func MessageText(msg *tgbotapi.Message) string {
+	if msg == nil {
+		return ""
+	}
+	if msg.Text != "" {
+		return msg.Text
+	}
+	if msg.Caption != "" {
+		return msg.Caption
+	}
+	return describeNonTextMessage(msg)
+}
+
+// describeNonTextMessage описывает текстом сообщение без текста и подписи, чтобы оно попало
+// в историю и контекст LLM. Для неподдерживаемых типов возвращает пустую строку.
+func describeNonTextMessage(msg *tgbotapi.Message) string {
+	switch {
+	case msg.Poll != nil:
+		return describePoll(msg.Poll)
+	case msg.Dice != nil:
+		return describeDice(msg.Dice)
+	case msg.Venue != nil && messageTextPrivacy.storeLocation:
+		return describeLocation(msg.Venue.Title, msg.Venue.Address, msg.Venue.Location)
+	case msg.Location != nil && messageTextPrivacy.storeLocation:
+		return describeLocation("", "", *msg.Location)
+	case msg.Contact != nil:
+		return describeContact(msg.Contact)
+	}
+	return ""
+}
+
+// describeContact описывает присланный контакт. Номер телефона не сохраняется никогда,
+// имя - только при STORE_CONTACT_NAMES.
+func describeContact(contact *tgbotapi.Contact) string {
+	name := strings.TrimSpace(contact.FirstName + " " + contact.LastName)
+	if !messageTextPrivacy.storeContactNames || name == "" {
+		return "[поделился контактом]"
+	}
+	return "[поделился контактом: " + name + "]"
+}
+
+// describeLocation описывает геопозицию или место, например
+// "[поделился локацией: Кафе, ул. Ленина 1, 55.751244, 37.618423]".
+func describeLocation(title, address string, location tgbotapi.Location) string {
+	var parts []string
+	for _, part := range []string{title, address} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	parts = append(parts, fmt.Sprintf("%.6f, %.6f", location.Latitude, location.Longitude))
+	return "[поделился локацией: " + strings.Join(parts, ", ") + "]"
+}
+
+// diceActions - описание броска по эмодзи Telegram Dice.
+var diceActions = map[string]string{
+	"🎲": "бросил кубик",
+	"🎯": "метнул дротик",
+	"🏀": "бросил мяч в кольцо",
+	"⚽": "ударил по мячу",
+	"🎳": "бросил шар в боулинге",
+	"🎰": "крутанул слот-машину",
+}
+
+// describeDice возвращает результат броска, например "[🎲 бросил кубик: 5]".
+func describeDice(dice *tgbotapi.Dice) string {
+	action, ok := diceActions[dice.Emoji]
+	if !ok {
+		action = "бросил"
+	}
+	return fmt.Sprintf("[%s %s: %d]", dice.Emoji, action, dice.Value)
+}
+
+// describePoll возвращает вопрос и варианты ответа опроса одной строкой.
+func describePoll(poll *tgbotapi.Poll) string {
+	kind := "опрос"
+	if poll.Type == "quiz" {
+		kind = "викторина"
+	}
+	options := make([]string, 0, len(poll.Options))
+	for _, option := range poll.Options {
+		options = append(options, option.Text)
+	}
+	return fmt.Sprintf("[%s] %s (варианты: %s)", kind, poll.Question, strings.Join(options, "; "))
+}
+
+// MessageEntities возвращает сущности, соответствующие MessageText (Entities или CaptionEntities).
+func MessageEntities(msg *tgbotapi.Message) []tgbotapi.MessageEntity {
+	if msg == nil {
+		return nil
+	}
+	if msg.Text != "" {
+		return msg.Entities
+	}
+	return msg.CaptionEntities
+}
+
+// isQuotableText сообщает, подходит ли текст для показа как "случайное сообщение":
+// не пустой и не команда бота.
+func isQuotableText(text string) bool {
+	text = strings.TrimSpace(text)
+	return text != "" && !strings.HasPrefix(text, "/")
+}
+
+// unknownForwardSource - автор пересланного сообщения, о котором Telegram ничего не сообщил.
+const unknownForwardSource = "неизвестного отправителя"
+
+// ForwardSource возвращает имя автора оригинала для пересланного сообщения
+// (пользователь, канал или скрытый отправитель). Для обычных сообщений - пустая строка.
+// Для пересланного сообщения результат никогда не пустой: без имени - unknownForwardSource.
+func ForwardSource(msg *tgbotapi.Message) string {
+	if msg == nil {
+		return ""
+	}
+	var source string
+	switch {
+	case msg.ForwardFrom != nil:
+		source = firstNonEmpty(usernameMention(msg.ForwardFrom.UserName),
+			strings.TrimSpace(msg.ForwardFrom.FirstName+" "+msg.ForwardFrom.LastName), msg.ForwardSenderName)
+	case msg.ForwardFromChat != nil:
+		source = firstNonEmpty(msg.ForwardFromChat.Title, usernameMention(msg.ForwardFromChat.UserName),
+			strings.TrimSpace(msg.ForwardFromChat.FirstName+" "+msg.ForwardFromChat.LastName), msg.ForwardSenderName)
+	case msg.ForwardSenderName != "":
+		source = msg.ForwardSenderName
+	case msg.ForwardDate == 0:
+		return "" // Не пересланное сообщение
+	}
+	if source == "" {
+		return unknownForwardSource
+	}
+	return source
+}
+
+// usernameMention возвращает "@username" или пустую строку, если username не задан.
+func usernameMention(username string) string {
+	if username == "" {
+		return ""
+	}
+	return "@" + username
+}
+
+// firstNonEmpty возвращает первую непустую строку.
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,32 @@
+package storage
+
+import (
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestForwardSource(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  *tgbotapi.Message
+		want string
+	}{
+		{name: "не пересланное", msg: &tgbotapi.Message{Text: "привет"}, want: ""},
+		{name: "пользователь с username", msg: &tgbotapi.Message{ForwardFrom: &tgbotapi.User{UserName: "user", FirstName: "Имя"}, ForwardDate: 1}, want: "@user"},
+		{name: "пользователь без username", msg: &tgbotapi.Message{ForwardFrom: &tgbotapi.User{FirstName: "Имя", LastName: "Фамилия"}, ForwardDate: 1}, want: "Имя Фамилия"},
+		{name: "канал с названием", msg: &tgbotapi.Message{ForwardFromChat: &tgbotapi.Chat{Title: "Канал", UserName: "channel"}, ForwardDate: 1}, want: "Канал"},
+		{name: "канал без названия", msg: &tgbotapi.Message{ForwardFromChat: &tgbotapi.Chat{UserName: "channel"}, ForwardDate: 1}, want: "@channel"},
+		{name: "чат без названия и username", msg: &tgbotapi.Message{ForwardFromChat: &tgbotapi.Chat{}, ForwardSenderName: "Подпись", ForwardDate: 1}, want: "Подпись"},
+		{name: "скрытый отправитель", msg: &tgbotapi.Message{ForwardSenderName: "Аноним", ForwardDate: 1}, want: "Аноним"},
+		{name: "без сведений об авторе", msg: &tgbotapi.Message{ForwardFromChat: &tgbotapi.Chat{}, ForwardDate: 1}, want: unknownForwardSource},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ForwardSource(tt.msg); got != tt.want {
+				t.Errorf("ForwardSource() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
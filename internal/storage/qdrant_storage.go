@@ -26,13 +26,22 @@ import (
 
 // QdrantStorage реализует HistoryStorage с использованием Qdrant.
 type QdrantStorage struct {
-	client         qdrant.PointsClient // Клиент для операций с точками
-	collectionName string
-	timeout        time.Duration
-	geminiClient   *gemini.Client // Клиент Gemini для получения эмбеддингов
-	debug          bool
+	client            qdrant.PointsClient      // Клиент для операций с точками
+	collectionsClient qdrant.CollectionsClient // Клиент для операций с коллекцией (информация, индексы)
+	collectionName    string
+	timeout           time.Duration
+	geminiClient      *gemini.Client // Клиент Gemini для получения эмбеддингов
+	debug             bool
 	// НОВЫЙ ПОЛЕ: Размер чанка для импорта
 	importChunkSize int
+	// Сколько запросов эмбеддингов выполняется параллельно при импорте
+	importConcurrency int
+	importVerify      bool // IMPORT_VERIFY: ждать Upsert и проверять, что точки действительно сохранены
+	// Предохранитель для запросов эмбеддингов при записи сообщений
+	embeddingBreaker *embeddingBreaker
+	// Пространство имен для UUID v5 точек (разделяет развертывания в общей коллекции)
+	uuidNamespace uuid.UUID
+	storeRaw      bool // Сохранять полный JSON сообщения в payload raw_message (STORE_RAW_UPDATES)
 	// Мьютекс не нужен для операций с Qdrant, но может понадобиться для внутренних кешей, если они будут
 	// mutex          sync.RWMutex
 }
@@ -53,13 +62,12 @@ type MessagePayload struct {
 	ImportSource   string `json:"import_source"`              // Источник импорта ("live", "batch_old")
 	UniqueID       string `json:"unique_id"`                  // Уникальный ID сообщения (chat_id + message_id)
 	Role           string `json:"role,omitempty"`             // Роль отправителя ("user", "model")
+	ForwardedFrom  string `json:"forwarded_from,omitempty"`   // Автор оригинала для пересланных сообщений
 }
 
-// NewQdrantStorage создает новый экземпляр QdrantStorage.
-func NewQdrantStorage(cfg *config.Config, geminiClient *gemini.Client) (*QdrantStorage, error) {
-	log.Printf("[QdrantStorage] Инициализация клиента Qdrant для эндпоинта: %s, коллекция: %s", cfg.QdrantEndpoint, cfg.QdrantCollection)
-
-	// --- Подключение к Qdrant ---
+// dialQdrant открывает gRPC-соединение с Qdrant по QDRANT_ENDPOINT (TLS для https, API Key при наличии).
+// Закрывать соединение должен вызывающий.
+func dialQdrant(cfg *config.Config) (*grpc.ClientConn, error) {
 	parsedURL, err := url.Parse(cfg.QdrantEndpoint)
 	if err != nil {
 		log.Printf("[QdrantStorage ERROR] Не удалось разобрать Qdrant Endpoint URL '%s': %v", cfg.QdrantEndpoint, err)
@@ -112,13 +120,36 @@ func NewQdrantStorage(cfg *config.Config, geminiClient *gemini.Client) (*QdrantS
 		log.Printf("[QdrantStorage ERROR] Не удалось подключиться к Qdrant (%s): %v", qdrantAddr, err)
 		return nil, fmt.Errorf("ошибка подключения к Qdrant gRPC (%s): %w", qdrantAddr, err)
 	}
-	// defer conn.Close() // Закрытие соединения будет при остановке бота
+	return conn, nil
+}
+
+// NewQdrantStorage создает новый экземпляр QdrantStorage.
+func NewQdrantStorage(cfg *config.Config, geminiClient *gemini.Client) (*QdrantStorage, error) {
+	log.Printf("[QdrantStorage] Инициализация клиента Qdrant для эндпоинта: %s, коллекция: %s", cfg.QdrantEndpoint, cfg.QdrantCollection)
+
+	conn, err := dialQdrant(cfg)
+	if err != nil {
+		return nil, err
+	}
+	// Закрытие соединения будет при остановке бота
 
 	pointsClient := qdrant.NewPointsClient(conn)
 	collectionsClient := qdrant.NewCollectionsClient(conn) // Нужен для проверки/создания коллекции
 
 	timeout := time.Duration(cfg.QdrantTimeoutSec) * time.Second
 
+	distance := qdrantDistances[cfg.QdrantDistance] // Значение проверено в config.LoadConfig
+
+	uuidNamespace := uuid.NameSpaceDNS
+	if cfg.QdrantUUIDNamespace != "" {
+		uuidNamespace, err = uuid.Parse(cfg.QdrantUUIDNamespace)
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("некорректный QDRANT_UUID_NAMESPACE '%s': %w", cfg.QdrantUUIDNamespace, err)
+		}
+		log.Printf("[QdrantStorage] Используется пространство имен UUID точек: %s", uuidNamespace)
+	}
+
 	// --- Проверка/Создание Коллекции ---
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
@@ -146,29 +177,56 @@ func NewQdrantStorage(cfg *config.Config, geminiClient *gemini.Client) (*QdrantS
 		}
 	}
 
+	if collectionExists {
+		// Метрику нельзя поменять у существующей коллекции, поэтому только предупреждаем о расхождении
+		infoResp, err := collectionsClient.Get(listCtx, &qdrant.GetCollectionInfoRequest{CollectionName: cfg.QdrantCollection})
+		if err != nil {
+			log.Printf("[QdrantStorage WARN] Не удалось получить параметры коллекции '%s': %v", cfg.QdrantCollection, err)
+		} else if params := infoResp.GetResult().GetConfig().GetParams().GetVectorsConfig().GetParams(); params != nil {
+			if params.GetDistance() != distance {
+				log.Printf("[QdrantStorage WARN] Коллекция '%s' создана с метрикой %s, а в конфиге указана %s (QDRANT_DISTANCE=%s). Будет использоваться метрика коллекции; для смены пересоздайте коллекцию.",
+					cfg.QdrantCollection, params.GetDistance(), distance, cfg.QdrantDistance)
+			}
+			// Размерность эмбеддингов меняется вместе с моделью, а Upsert с другой размерностью в
+			// существующую коллекцию не проходит. Проверяем заранее, чтобы не терять сообщения молча.
+			if cfg.QdrantCheckDimension {
+				vectorSize, err := testEmbeddingSize(ctx, geminiClient)
+				if err != nil {
+					conn.Close()
+					return nil, fmt.Errorf("не удалось проверить размерность векторов коллекции Qdrant: %w", err)
+				}
+				if vectorSize != params.GetSize() {
+					log.Printf("[QdrantStorage ERROR] Размерность векторов коллекции '%s' (%d) не совпадает с размерностью модели %s (%d).",
+						cfg.QdrantCollection, params.GetSize(), cfg.GeminiEmbeddingModelName, vectorSize)
+					conn.Close()
+					return nil, fmt.Errorf("%w: коллекция '%s' (%d), модель %s (%d); укажите другую QDRANT_COLLECTION или пересоздайте коллекцию",
+						ErrVectorDimensionMismatch, cfg.QdrantCollection, params.GetSize(), cfg.GeminiEmbeddingModelName, vectorSize)
+				}
+				log.Printf("[QdrantStorage] Размерность векторов коллекции '%s' совпадает с моделью эмбеддингов: %d", cfg.QdrantCollection, vectorSize)
+			}
+		}
+		if cfg.QdrantHnswM > 0 || cfg.QdrantHnswEfConstruct > 0 {
+			log.Printf("[QdrantStorage] Коллекция '%s' уже существует: QDRANT_HNSW_M/QDRANT_HNSW_EF_CONSTRUCT применяются только при создании и будут проигнорированы.", cfg.QdrantCollection)
+		}
+	}
+
 	if !collectionExists {
 		log.Printf("[QdrantStorage] Коллекция '%s' не найдена. Попытка создания...", cfg.QdrantCollection)
 		// Получим размерность, сгенерировав эмбеддинг для тестовой строки.
 		// Важно: Убедитесь, что Gemini клиент уже инициализирован и работает.
-		testEmbeddings, err := geminiClient.GetEmbeddingsBatch(ctx, []string{"test"})
+		vectorSize, err := testEmbeddingSize(ctx, geminiClient)
 		if err != nil {
-			log.Printf("[QdrantStorage ERROR] Не удалось получить тестовый эмбеддинг для определения размерности: %v", err)
+			conn.Close()
 			return nil, fmt.Errorf("не удалось определить размерность вектора для коллекции Qdrant: %w", err)
 		}
-		if len(testEmbeddings) != 1 || len(testEmbeddings[0]) == 0 {
-			log.Printf("[QdrantStorage ERROR] Получен некорректный результат эмбеддинга для теста (ожидался 1 непустой вектор): %d векторов", len(testEmbeddings))
-			return nil, fmt.Errorf("не удалось определить размерность вектора: неожиданный результат от GetEmbeddingsBatch")
-		}
-		testEmbedding := testEmbeddings[0] // Берем первый (и единственный) эмбеддинг
-		vectorSize := uint64(len(testEmbedding))
-		log.Printf("[QdrantStorage] Определена размерность векторов: %d", vectorSize)
+		log.Printf("[QdrantStorage] Определена размерность векторов: %d, метрика: %s", vectorSize, distance)
 
 		// --- НОВЫЙ КОД: Добавляем параметры оптимизации из конфига ---
 		vectorsConfig := &qdrant.VectorsConfig{
 			Config: &qdrant.VectorsConfig_Params{
 				Params: &qdrant.VectorParams{
 					Size:     vectorSize,
-					Distance: qdrant.Distance_Cosine,
+					Distance: distance,          // Метрика из конфига (QDRANT_DISTANCE)
 					OnDisk:   &cfg.QdrantOnDisk, // Используем флаг из конфига
 				},
 			},
@@ -190,6 +248,21 @@ func NewQdrantStorage(cfg *config.Config, geminiClient *gemini.Client) (*QdrantS
 		}
 		// --- КОНЕЦ НОВОГО КОДА ---
 
+		// Параметры HNSW-индекса (0 - значение Qdrant по умолчанию)
+		var hnswConfig *qdrant.HnswConfigDiff
+		if cfg.QdrantHnswM > 0 || cfg.QdrantHnswEfConstruct > 0 {
+			hnswConfig = &qdrant.HnswConfigDiff{}
+			if cfg.QdrantHnswM > 0 {
+				m := uint64(cfg.QdrantHnswM)
+				hnswConfig.M = &m
+			}
+			if cfg.QdrantHnswEfConstruct > 0 {
+				efConstruct := uint64(cfg.QdrantHnswEfConstruct)
+				hnswConfig.EfConstruct = &efConstruct
+			}
+			log.Printf("[QdrantStorage] Параметры HNSW: m=%d, ef_construct=%d (0 - по умолчанию)", cfg.QdrantHnswM, cfg.QdrantHnswEfConstruct)
+		}
+
 		// Добавляем API ключ в контекст для запроса Create, если он используется
 		createCtx, createCancel := context.WithTimeout(context.Background(), timeout)
 		defer createCancel()
@@ -202,7 +275,7 @@ func NewQdrantStorage(cfg *config.Config, geminiClient *gemini.Client) (*QdrantS
 		_, err = collectionsClient.Create(createReqCtx, &qdrant.CreateCollection{ // Используем createReqCtx
 			CollectionName: cfg.QdrantCollection,
 			VectorsConfig:  vectorsConfig, // Используем созданный vectorsConfig
-			// HnswConfig:       nil, // Можно настроить HNSW отдельно
+			HnswConfig:     hnswConfig,    // Параметры HNSW из конфига (nil - по умолчанию)
 			// OptimizersConfig: nil, // Можно настроить оптимизаторы
 			QuantizationConfig: quantizationConfig, // Используем созданный quantizationConfig
 		})
@@ -214,55 +287,140 @@ func NewQdrantStorage(cfg *config.Config, geminiClient *gemini.Client) (*QdrantS
 		log.Printf("[QdrantStorage] Коллекция '%s' успешно создана.", cfg.QdrantCollection)
 	}
 
+	// --- Индексы по полям payload ---
+	// Фильтры по chat_id (поиск, очистка) и date без индексов превращаются в полный перебор коллекции
+	ensurePayloadIndexes(collectionsClient, pointsClient, cfg, timeout)
+
 	log.Println("[QdrantStorage] Клиент Qdrant успешно инициализирован.")
 	return &QdrantStorage{
-		client:         pointsClient,
-		collectionName: cfg.QdrantCollection,
-		timeout:        timeout,
-		geminiClient:   geminiClient,
-		debug:          cfg.Debug,
+		client:            pointsClient,
+		collectionsClient: collectionsClient,
+		collectionName:    cfg.QdrantCollection,
+		timeout:           timeout,
+		geminiClient:      geminiClient,
+		debug:             cfg.Debug,
 		// НОВОЕ ПОЛЕ:
-		importChunkSize: cfg.ImportChunkSize, // Сохраняем размер чанка
+		importChunkSize:   cfg.ImportChunkSize, // Сохраняем размер чанка
+		importConcurrency: cfg.ImportEmbeddingConcurrency,
+		importVerify:      cfg.ImportVerify,
+		embeddingBreaker:  newEmbeddingBreaker(cfg.EmbeddingBreakerThreshold, cfg.EmbeddingBreakerCooldown),
+		uuidNamespace:     uuidNamespace,
+		storeRaw:          cfg.StoreRawUpdates,
 	}, nil
 }
 
+// payloadIndexFields - поля payload, по которым строятся индексы, и их типы.
+var payloadIndexFields = []struct {
+	name      string
+	fieldType qdrant.FieldType
+}{
+	{"chat_id", qdrant.FieldType_FieldTypeInteger},
+	{"date", qdrant.FieldType_FieldTypeInteger},
+}
+
+// ensurePayloadIndexes создает недостающие индексы по полям payload.
+// Ошибки не фатальны: без индекса фильтрация работает, просто медленнее.
+func ensurePayloadIndexes(collectionsClient qdrant.CollectionsClient, pointsClient qdrant.PointsClient, cfg *config.Config, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	reqCtx := ctx
+	if cfg.QdrantAPIKey != "" {
+		md := metadata.New(map[string]string{"api-key": cfg.QdrantAPIKey})
+		reqCtx = metadata.NewOutgoingContext(ctx, md)
+	}
+
+	// Узнаем, какие индексы уже есть, чтобы не пересоздавать их при каждом запуске
+	existing := map[string]*qdrant.PayloadSchemaInfo{}
+	if infoResp, err := collectionsClient.Get(reqCtx, &qdrant.GetCollectionInfoRequest{CollectionName: cfg.QdrantCollection}); err != nil {
+		log.Printf("[QdrantStorage WARN] Не удалось получить схему payload коллекции '%s': %v", cfg.QdrantCollection, err)
+	} else {
+		existing = infoResp.GetResult().GetPayloadSchema()
+	}
+
+	wait := true
+	for _, field := range payloadIndexFields {
+		if _, ok := existing[field.name]; ok {
+			continue
+		}
+		fieldType := field.fieldType
+		_, err := pointsClient.CreateFieldIndex(reqCtx, &qdrant.CreateFieldIndexCollection{
+			CollectionName: cfg.QdrantCollection,
+			FieldName:      field.name,
+			FieldType:      &fieldType,
+			Wait:           &wait,
+		})
+		if err != nil {
+			log.Printf("[QdrantStorage WARN] Не удалось создать индекс payload '%s': %v", field.name, err)
+			continue
+		}
+		log.Printf("[QdrantStorage] Создан индекс payload '%s' (%s).", field.name, fieldType)
+	}
+}
+
+// qdrantDistances сопоставляет значения QDRANT_DISTANCE с метриками Qdrant.
+var qdrantDistances = map[string]qdrant.Distance{
+	"cosine": qdrant.Distance_Cosine,
+	"dot":    qdrant.Distance_Dot,
+	"euclid": qdrant.Distance_Euclid,
+}
+
+// testEmbeddingSize получает эмбеддинг тестовой строки и возвращает его размерность.
+func testEmbeddingSize(ctx context.Context, geminiClient *gemini.Client) (uint64, error) {
+	testEmbeddings, err := geminiClient.GetEmbeddingsBatch(ctx, []string{"test"})
+	if err != nil {
+		log.Printf("[QdrantStorage ERROR] Не удалось получить тестовый эмбеддинг для определения размерности: %v", err)
+		return 0, err
+	}
+	if len(testEmbeddings) != 1 || len(testEmbeddings[0]) == 0 {
+		log.Printf("[QdrantStorage ERROR] Получен некорректный результат эмбеддинга для теста (ожидался 1 непустой вектор): %d векторов", len(testEmbeddings))
+		return 0, fmt.Errorf("неожиданный результат от GetEmbeddingsBatch")
+	}
+	return uint64(len(testEmbeddings[0])), nil // Берем первый (и единственный) эмбеддинг
+}
+
 // --- Реализация интерфейса HistoryStorage (частичная/адаптированная) ---
 
 // AddMessage добавляет одно сообщение в хранилище Qdrant.
 func (qs *QdrantStorage) AddMessage(chatID int64, message *tgbotapi.Message) {
+	qs.AddMessageWithRaw(chatID, message, nil)
+}
+
+// AddMessageWithRaw добавляет сообщение вместе с его полным JSON (payload raw_message, только с STORE_RAW_UPDATES).
+func (qs *QdrantStorage) AddMessageWithRaw(chatID int64, message *tgbotapi.Message, raw json.RawMessage) {
 	log.Printf("[Qdrant DEBUG] Попытка добавить сообщение ID %d в чат %d", message.MessageID, chatID)
+	message = sanitizeMessage(message)
 
-	// Игнорируем сообщения без текста
-	if message.Text == "" && message.Caption == "" {
+	// Получаем текст сообщения (текст или подпись, если текст пуст)
+	messageText := MessageText(message)
+	if messageText == "" {
 		log.Printf("[Qdrant DEBUG] Сообщение ID %d в чате %d не содержит текста, пропускаем", message.MessageID, chatID)
 		return
 	}
 
-	// Получаем текст сообщения (текст или подпись, если текст пуст)
-	messageText := message.Text
-	if messageText == "" {
-		messageText = message.Caption
-		log.Printf("[Qdrant DEBUG] Используем Caption вместо Text для сообщения ID %d", message.MessageID)
+	// 1. Получаем эмбеддинг текста (если предохранитель не разомкнут)
+	if !qs.embeddingBreaker.Allow() {
+		return
 	}
-
-	// 1. Получаем эмбеддинг текста
 	log.Printf("[Qdrant DEBUG] Запрос эмбеддинга для сообщения ID %d, текст: %s...", message.MessageID, truncateString(messageText, 20))
 	ctxEmb, cancelEmb := context.WithTimeout(context.Background(), qs.timeout)
 	defer cancelEmb()
 	embeddings, err := qs.geminiClient.GetEmbeddingsBatch(ctxEmb, []string{messageText})
 	if err != nil {
 		log.Printf("[Qdrant ERROR] Ошибка получения эмбеддинга для сообщения ID %d: %v", message.MessageID, err)
+		qs.embeddingBreaker.RecordFailure()
 		return // Прерываем, если не удалось получить эмбеддинг
 	}
 	if len(embeddings) != 1 || len(embeddings[0]) == 0 {
 		log.Printf("[Qdrant ERROR] Получен некорректный результат эмбеддинга для сообщения ID %d (ожидался 1 непустой вектор): %d векторов", message.MessageID, len(embeddings))
+		qs.embeddingBreaker.RecordFailure()
 		return
 	}
+	qs.embeddingBreaker.RecordSuccess()
 	embedding := embeddings[0] // Берем первый (и единственный) эмбеддинг
 	log.Printf("[Qdrant DEBUG] Получен эмбеддинг размером %d для сообщения ID %d", len(embedding), message.MessageID)
 
 	// 2. Создаем payload и ID для сообщения
-	payloadMap, pointIDStr := qs.createPayload(chatID, message, "live")
+	payloadMap, pointIDStr := qs.createPayload(chatID, message, "live", raw)
 	if payloadMap == nil {
 		log.Printf("[Qdrant ERROR] Не удалось создать payload для сообщения ID %d", message.MessageID)
 		return
@@ -320,19 +478,37 @@ func (qs *QdrantStorage) AddMessage(chatID int64, message *tgbotapi.Message) {
 	log.Printf("[Qdrant OK] Сообщение ID %d успешно добавлено в коллекцию %s", message.MessageID, qs.collectionName)
 }
 
+// UpdateMessage перезаписывает точку сообщения после редактирования.
+// ID точки детерминирован (chat_id + message_id), поэтому Upsert заменяет старый текст
+// и эмбеддинг новым, а не создает дубликат.
+func (qs *QdrantStorage) UpdateMessage(chatID int64, message *tgbotapi.Message) {
+	log.Printf("[Qdrant DEBUG] Обновление отредактированного сообщения ID %d в чате %d", message.MessageID, chatID)
+	qs.AddMessage(chatID, message)
+}
+
+// messagePointUUID возвращает детерминированный UUID точки Qdrant для сообщения.
+// Одинаков для живых сообщений и импорта, поэтому повторная запись обновляет ту же точку.
+// Пространство имен задается QDRANT_UUID_NAMESPACE (по умолчанию uuid.NameSpaceDNS).
+func (qs *QdrantStorage) messagePointUUID(chatID int64, messageID int64) string {
+	uniqueIDStr := fmt.Sprintf("%d_%d", chatID, messageID)
+	return uuid.NewSHA1(qs.uuidNamespace, []byte(uniqueIDStr)).String()
+}
+
 // createPayload конвертирует сообщение и метаданные в map[string]*qdrant.Value для Qdrant.
-func (qs *QdrantStorage) createPayload(chatID int64, message *tgbotapi.Message, importSource string) (map[string]*qdrant.Value, string) {
+// raw - полный JSON сообщения до очистки (nil - не сохранять).
+func (qs *QdrantStorage) createPayload(chatID int64, message *tgbotapi.Message, importSource string, raw json.RawMessage) (map[string]*qdrant.Value, string) {
 
 	// Создаем уникальный ID для сообщения
 	uniqueID := fmt.Sprintf("%d_%d", chatID, message.MessageID)
 
 	payload := &MessagePayload{
-		ChatID:       chatID,
-		MessageID:    message.MessageID,
-		Text:         message.Text,
-		Date:         message.Date,
-		ImportSource: importSource,
-		UniqueID:     uniqueID, // Сохраняем уникальный ID и в пейлоаде
+		ChatID:        chatID,
+		MessageID:     message.MessageID,
+		Text:          MessageText(message), // Текст или подпись медиа
+		Date:          message.Date,
+		ImportSource:  importSource,
+		UniqueID:      uniqueID, // Сохраняем уникальный ID и в пейлоаде
+		ForwardedFrom: ForwardSource(message),
 	}
 	if message.From != nil {
 		payload.UserID = message.From.ID
@@ -343,9 +519,9 @@ func (qs *QdrantStorage) createPayload(chatID int64, message *tgbotapi.Message,
 	if message.ReplyToMessage != nil {
 		payload.ReplyToMsgID = message.ReplyToMessage.MessageID
 	}
-	if len(message.Entities) > 0 {
+	if entities := MessageEntities(message); len(entities) > 0 {
 		// Сериализуем entities в JSON для хранения
-		entitiesBytes, err := json.Marshal(message.Entities)
+		entitiesBytes, err := json.Marshal(entities)
 		if err == nil {
 			payload.Entities = entitiesBytes
 		} else {
@@ -372,12 +548,21 @@ func (qs *QdrantStorage) createPayload(chatID int64, message *tgbotapi.Message,
 		qdrantPayload["entities_json"] = &qdrant.Value{Kind: &qdrant.Value_StringValue{StringValue: string(payload.Entities)}}
 	}
 
+	if payload.ForwardedFrom != "" {
+		qdrantPayload["forwarded_from"] = &qdrant.Value{Kind: &qdrant.Value_StringValue{StringValue: payload.ForwardedFrom}}
+	}
+
+	if qs.storeRaw && len(raw) > 0 {
+		qdrantPayload["raw_message"] = &qdrant.Value{Kind: &qdrant.Value_StringValue{StringValue: string(raw)}}
+	}
+
 	// Добавляем роль (если она не "user", или если хотим хранить всегда)
 	if payload.Role != "user" {
 		qdrantPayload["role"] = &qdrant.Value{Kind: &qdrant.Value_StringValue{StringValue: payload.Role}}
 	}
 
-	return qdrantPayload, uniqueID
+	// ID точки должен быть корректным UUID: используем тот же UUID v5, что и при импорте
+	return qdrantPayload, qs.messagePointUUID(chatID, int64(message.MessageID))
 }
 
 // AddMessagesToContext добавляет несколько сообщений.
@@ -455,12 +640,320 @@ func (qs *QdrantStorage) ClearChatHistory(chatID int64) {
 	}
 }
 
+// ClearUserHistory удаляет из коллекции все точки пользователя в чате (фильтр по chat_id и user_id).
+func (qs *QdrantStorage) ClearUserHistory(chatID int64, userID int64) error {
+	log.Printf("[QdrantStorage] Удаление сообщений пользователя %d в чате %d...", userID, chatID)
+	ctx, cancel := context.WithTimeout(context.Background(), qs.timeout)
+	defer cancel()
+	deleteCtx := ctx
+	if apiKey := qs.getApiKeyFromConfig(); apiKey != "" {
+		md := metadata.New(map[string]string{"api-key": apiKey})
+		deleteCtx = metadata.NewOutgoingContext(ctx, md)
+	}
+
+	waitDelete := true
+	_, err := qs.client.Delete(deleteCtx, &qdrant.DeletePoints{
+		CollectionName: qs.collectionName,
+		Points: &qdrant.PointsSelector{
+			PointsSelectorOneOf: &qdrant.PointsSelector_Filter{
+				Filter: &qdrant.Filter{
+					Must: []*qdrant.Condition{
+						{
+							ConditionOneOf: &qdrant.Condition_Field{
+								Field: &qdrant.FieldCondition{
+									Key:   "chat_id",
+									Match: &qdrant.Match{MatchValue: &qdrant.Match_Integer{Integer: chatID}},
+								},
+							},
+						},
+						{
+							ConditionOneOf: &qdrant.Condition_Field{
+								Field: &qdrant.FieldCondition{
+									Key:   "user_id",
+									Match: &qdrant.Match{MatchValue: &qdrant.Match_Integer{Integer: userID}},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		Wait: &waitDelete,
+	})
+	if err != nil {
+		log.Printf("[QdrantStorage ERROR ClearUser Chat %d] Ошибка удаления сообщений пользователя %d: %v", chatID, userID, err)
+		return fmt.Errorf("ошибка удаления сообщений пользователя %d из Qdrant: %w", userID, err)
+	}
+	log.Printf("[QdrantStorage] Сообщения пользователя %d в чате %d удалены из Qdrant.", userID, chatID)
+	return nil
+}
+
+// randomSampleSize - сколько случайных точек запрашивать за раз в GetRandomMessage,
+// чтобы было из чего выбрать, если часть окажется командами или сообщениями ботов.
+const randomSampleSize = 10
+
+// GetRandomMessage возвращает случайное сообщение чата через случайную выборку (Query Sample) Qdrant.
+func (qs *QdrantStorage) GetRandomMessage(chatID int64) (*types.Message, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), qs.timeout)
+	defer cancel()
+	queryCtx := ctx
+	if apiKey := qs.getApiKeyFromConfig(); apiKey != "" {
+		md := metadata.New(map[string]string{"api-key": apiKey})
+		queryCtx = metadata.NewOutgoingContext(ctx, md)
+	}
+
+	limit := uint64(randomSampleSize)
+	resp, err := qs.client.Query(queryCtx, &qdrant.QueryPoints{
+		CollectionName: qs.collectionName,
+		Query:          &qdrant.Query{Variant: &qdrant.Query_Sample{Sample: qdrant.Sample_Random}},
+		Filter: &qdrant.Filter{
+			Must: []*qdrant.Condition{
+				{
+					ConditionOneOf: &qdrant.Condition_Field{
+						Field: &qdrant.FieldCondition{
+							Key:   "chat_id",
+							Match: &qdrant.Match{MatchValue: &qdrant.Match_Integer{Integer: chatID}},
+						},
+					},
+				},
+			},
+		},
+		Limit:       &limit,
+		WithPayload: &qdrant.WithPayloadSelector{SelectorOptions: &qdrant.WithPayloadSelector_Enable{Enable: true}},
+	})
+	if err != nil {
+		log.Printf("[QdrantStorage ERROR RandomMessage Chat %d] Ошибка случайной выборки: %v", chatID, err)
+		return nil, fmt.Errorf("ошибка случайной выборки сообщений из Qdrant: %w", err)
+	}
+
+	for _, point := range resp.GetResult() {
+		msg, err := qs.payloadToMessage(point.GetPayload())
+		if err != nil {
+			continue
+		}
+		if !msg.IsBot && isQuotableText(msg.Text) {
+			return &msg, nil
+		}
+	}
+	return nil, nil
+}
+
+// statsScrollPageSize - сколько точек запрашивается за один Scroll при подсчете статистики.
+const statsScrollPageSize = 1000
+
+// GetChatStats считает статистику чата за период, постранично перебирая точки чата с date >= since.
+// Загружаются только поля, нужные для подсчета, без текста и векторов.
+func (qs *QdrantStorage) GetChatStats(chatID int64, since time.Time) (*ChatStats, error) {
+	builder := newChatStatsBuilder(since)
+	sinceUnix := float64(since.Unix())
+	limit := uint32(statsScrollPageSize)
+	filter := &qdrant.Filter{
+		Must: []*qdrant.Condition{
+			{
+				ConditionOneOf: &qdrant.Condition_Field{
+					Field: &qdrant.FieldCondition{
+						Key:   "chat_id",
+						Match: &qdrant.Match{MatchValue: &qdrant.Match_Integer{Integer: chatID}},
+					},
+				},
+			},
+			qdrant.NewRange("date", &qdrant.Range{Gte: &sinceUnix}),
+		},
+	}
+
+	var offset *qdrant.PointId
+	for {
+		resp, err := qs.scrollStatsPage(filter, offset, limit)
+		if err != nil {
+			log.Printf("[QdrantStorage ERROR Stats Chat %d] Ошибка Scroll: %v", chatID, err)
+			return nil, fmt.Errorf("ошибка получения сообщений для статистики из Qdrant: %w", err)
+		}
+		for _, point := range resp.GetResult() {
+			payload := point.GetPayload()
+			builder.add(
+				payload["user_id"].GetIntegerValue(),
+				payload["user_name"].GetStringValue(),
+				payload["first_name"].GetStringValue(),
+				payload["is_bot"].GetBoolValue(),
+				int(payload["date"].GetIntegerValue()),
+			)
+		}
+		offset = resp.GetNextPageOffset()
+		if offset == nil {
+			break
+		}
+	}
+	return builder.result(), nil
+}
+
+// scrollStatsPage запрашивает одну страницу точек для GetChatStats.
+func (qs *QdrantStorage) scrollStatsPage(filter *qdrant.Filter, offset *qdrant.PointId, limit uint32) (*qdrant.ScrollResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), qs.timeout)
+	defer cancel()
+	scrollCtx := ctx
+	if apiKey := qs.getApiKeyFromConfig(); apiKey != "" {
+		md := metadata.New(map[string]string{"api-key": apiKey})
+		scrollCtx = metadata.NewOutgoingContext(ctx, md)
+	}
+	return qs.client.Scroll(scrollCtx, &qdrant.ScrollPoints{
+		CollectionName: qs.collectionName,
+		Filter:         filter,
+		Offset:         offset,
+		Limit:          &limit,
+		WithPayload:    qdrant.NewWithPayloadInclude("user_id", "user_name", "first_name", "is_bot", "date"),
+	})
+}
+
+// DeleteMessage удаляет точку сообщения из коллекции по ее детерминированному UUID.
+func (qs *QdrantStorage) DeleteMessage(chatID int64, messageID int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), qs.timeout)
+	defer cancel()
+	deleteCtx := ctx
+	if apiKey := qs.getApiKeyFromConfig(); apiKey != "" {
+		md := metadata.New(map[string]string{"api-key": apiKey})
+		deleteCtx = metadata.NewOutgoingContext(ctx, md)
+	}
+
+	waitDelete := true
+	_, err := qs.client.Delete(deleteCtx, &qdrant.DeletePoints{
+		CollectionName: qs.collectionName,
+		Points: &qdrant.PointsSelector{
+			PointsSelectorOneOf: &qdrant.PointsSelector_Points{
+				Points: &qdrant.PointsIdsList{
+					Ids: []*qdrant.PointId{
+						{PointIdOptions: &qdrant.PointId_Uuid{Uuid: qs.messagePointUUID(chatID, int64(messageID))}},
+					},
+				},
+			},
+		},
+		Wait: &waitDelete,
+	})
+	if err != nil {
+		log.Printf("[QdrantStorage ERROR DeleteMessage Chat %d] Ошибка удаления сообщения %d: %v", chatID, messageID, err)
+		return fmt.Errorf("ошибка удаления сообщения %d из Qdrant: %w", messageID, err)
+	}
+	log.Printf("[QdrantStorage] Сообщение %d чата %d удалено из Qdrant.", messageID, chatID)
+	return nil
+}
+
+// GetRawMessage возвращает полный JSON сообщения из payload raw_message его точки.
+func (qs *QdrantStorage) GetRawMessage(chatID int64, messageID int) ([]byte, error) {
+	if !qs.storeRaw {
+		return nil, ErrNotSupported
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), qs.timeout)
+	defer cancel()
+	getCtx := ctx
+	if apiKey := qs.getApiKeyFromConfig(); apiKey != "" {
+		md := metadata.New(map[string]string{"api-key": apiKey})
+		getCtx = metadata.NewOutgoingContext(ctx, md)
+	}
+
+	resp, err := qs.client.Get(getCtx, &qdrant.GetPoints{
+		CollectionName: qs.collectionName,
+		Ids:            []*qdrant.PointId{{PointIdOptions: &qdrant.PointId_Uuid{Uuid: qs.messagePointUUID(chatID, int64(messageID))}}},
+		WithPayload:    qdrant.NewWithPayloadInclude("raw_message"),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения сообщения %d из Qdrant: %w", messageID, err)
+	}
+	for _, point := range resp.GetResult() {
+		if raw := point.GetPayload()["raw_message"].GetStringValue(); raw != "" {
+			return []byte(raw), nil
+		}
+	}
+	return nil, nil
+}
+
 // SaveAllChatHistories - Нерелевантно для Qdrant, возвращает nil.
 func (qs *QdrantStorage) SaveAllChatHistories() error {
 	// log.Printf("[QdrantStorage] SaveAllChatHistories вызван, но не требуется для Qdrant.")
 	return nil
 }
 
+// BackendName возвращает название реализации хранилища.
+func (qs *QdrantStorage) BackendName() string {
+	return "qdrant"
+}
+
+// SupportsVectorSearch - Qdrant ищет релевантные сообщения по эмбеддингам.
+func (qs *QdrantStorage) SupportsVectorSearch() bool {
+	return true
+}
+
+// --- Диагностика коллекции ---
+
+// CollectionStats - сводная информация о коллекции Qdrant для диагностики.
+type CollectionStats struct {
+	Name                string
+	Status              string // green / yellow / grey / red
+	OptimizerOK         bool
+	OptimizerError      string
+	PointsCount         uint64
+	VectorsCount        uint64
+	IndexedVectorsCount uint64
+	SegmentsCount       uint64
+}
+
+// GetCollectionInfo возвращает размер и состояние коллекции (обертка над RPC CollectionInfo).
+func (qs *QdrantStorage) GetCollectionInfo() (*CollectionStats, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), qs.timeout)
+	defer cancel()
+	reqCtx := ctx
+	if apiKey := qs.getApiKeyFromConfig(); apiKey != "" {
+		md := metadata.New(map[string]string{"api-key": apiKey})
+		reqCtx = metadata.NewOutgoingContext(ctx, md)
+	}
+
+	resp, err := qs.collectionsClient.Get(reqCtx, &qdrant.GetCollectionInfoRequest{CollectionName: qs.collectionName})
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения информации о коллекции '%s': %w", qs.collectionName, err)
+	}
+	return collectionStats(qs.collectionName, resp.GetResult()), nil
+}
+
+// collectionStats переводит ответ Qdrant о коллекции в CollectionStats.
+func collectionStats(name string, info *qdrant.CollectionInfo) *CollectionStats {
+	return &CollectionStats{
+		Name:                name,
+		Status:              strings.ToLower(info.GetStatus().String()),
+		OptimizerOK:         info.GetOptimizerStatus().GetOk(),
+		OptimizerError:      info.GetOptimizerStatus().GetError(),
+		PointsCount:         info.GetPointsCount(),
+		VectorsCount:        info.GetVectorsCount(),
+		IndexedVectorsCount: info.GetIndexedVectorsCount(),
+		SegmentsCount:       info.GetSegmentsCount(),
+	}
+}
+
+// ProbeQdrant проверяет доступность Qdrant и коллекции QDRANT_COLLECTION только запросами на чтение
+// (для CONFIG_CHECK): в отличие от NewQdrantStorage, не создает коллекцию и индексы.
+// Возвращает nil без ошибки, если Qdrant доступен, а коллекции еще нет (она будет создана при запуске).
+func ProbeQdrant(cfg *config.Config) (*CollectionStats, error) {
+	conn, err := dialQdrant(cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	collectionsClient := qdrant.NewCollectionsClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.QdrantTimeoutSec)*time.Second)
+	defer cancel()
+
+	existsResp, err := collectionsClient.CollectionExists(ctx, &qdrant.CollectionExistsRequest{CollectionName: cfg.QdrantCollection})
+	if err != nil {
+		return nil, fmt.Errorf("ошибка проверки коллекции Qdrant '%s': %w", cfg.QdrantCollection, err)
+	}
+	if !existsResp.GetResult().GetExists() {
+		return nil, nil
+	}
+	resp, err := collectionsClient.Get(ctx, &qdrant.GetCollectionInfoRequest{CollectionName: cfg.QdrantCollection})
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения информации о коллекции '%s': %w", cfg.QdrantCollection, err)
+	}
+	return collectionStats(cfg.QdrantCollection, resp.GetResult()), nil
+}
+
 // --- Функции для Семантического Поиска (не часть интерфейса HistoryStorage) ---
 
 // FindRelevantMessages ищет сообщения в Qdrant, семантически близкие к queryText.
@@ -598,15 +1091,41 @@ func (qs *QdrantStorage) payloadToMessage(payload map[string]*qdrant.Value) (typ
 		return types.Message{}, fmt.Errorf("отсутствует поле text")
 	}
 
+	// chat_id нужен для дедупликации с недавней историей (ключ ChatID:ID),
+	// без него сообщение из памяти дублирует то же сообщение из недавнего контекста
+	if val, ok := payload["chat_id"]; ok {
+		if intVal, isInt := val.GetKind().(*qdrant.Value_IntegerValue); isInt {
+			msg.ChatID = intVal.IntegerValue
+		}
+	}
+
 	// Восстанавливаем необязательные поля
 	if val, ok := payload["user_id"]; ok {
 		if intVal, isInt := val.GetKind().(*qdrant.Value_IntegerValue); isInt {
 			userIDInt = intVal.IntegerValue
-			_ = userIDInt // Подавляем ошибку 'unused variable', если ID не используется далее
-			// Можно использовать userIDInt для восстановления User в tgbotapi.Message, если нужно
+			msg.UserID = userIDInt
+		}
+	}
+	if val, ok := payload["user_name"]; ok {
+		if strVal, isStr := val.GetKind().(*qdrant.Value_StringValue); isStr {
+			msg.UserName = strVal.StringValue
+		}
+	}
+	if val, ok := payload["first_name"]; ok {
+		if strVal, isStr := val.GetKind().(*qdrant.Value_StringValue); isStr {
+			msg.FirstName = strVal.StringValue
+		}
+	}
+	if val, ok := payload["is_bot"]; ok {
+		if boolVal, isBool := val.GetKind().(*qdrant.Value_BoolValue); isBool {
+			msg.IsBot = boolVal.BoolValue
+		}
+	}
+	if val, ok := payload["forwarded_from"]; ok {
+		if strVal, isStr := val.GetKind().(*qdrant.Value_StringValue); isStr {
+			msg.ForwardedFrom = strVal.StringValue
 		}
 	}
-	// ... можно добавить восстановление user_name, first_name и т.д., если необходимо ...
 
 	// Embedding не восстанавливаем, т.к. он не нужен для возврата в виде Message
 	// msg.Embedding = ...
@@ -678,8 +1197,8 @@ func (qs *QdrantStorage) ImportMessagesFromJSONFile(chatID int64, filePath strin
 	// Мьютекс для безопасного доступа к счетчикам
 	var countMutex sync.Mutex
 
-	// Ограничение количества одновременных горутин для получения эмбеддингов (например, 10)
-	embeddingSemaphore := make(chan struct{}, 10)
+	// Ограничение количества одновременных горутин для получения эмбеддингов (IMPORT_EMBEDDING_CONCURRENCY)
+	embeddingSemaphore := make(chan struct{}, qs.importConcurrency)
 
 	totalProcessed := 0 // Общий счетчик обработанных сообщений из файла
 
@@ -696,7 +1215,7 @@ func (qs *QdrantStorage) ImportMessagesFromJSONFile(chatID int64, filePath strin
 		}
 
 		totalProcessed++
-		messageChunk = append(messageChunk, msg)
+		messageChunk = append(messageChunk, sanitizeImportedMessage(msg))
 
 		// Если чанк наполнен, обрабатываем его
 		if len(messageChunk) >= chunkSize {
@@ -763,6 +1282,14 @@ func (qs *QdrantStorage) ImportMessagesFromJSONFile(chatID int64, filePath strin
 
 	log.Printf("[Qdrant Import OK] Чат %d: Импорт из файла %s завершен. Всего прочитано: %d, Импортировано/Обновлено: %d, Пропущено (дубликаты/ошибки): %d.",
 		chatID, filePath, totalProcessed, importedCount, skippedCount)
+	// Итог проверки выводится только в режиме IMPORT_VERIFY: без него число импортированных - лишь число отправленных точек
+	if qs.importVerify {
+		if err != nil {
+			log.Printf("[Qdrant Import WARN] Чат %d: Импорт завершился с ошибкой, подтверждено проверкой (IMPORT_VERIFY) только %d точек: %v", chatID, importedCount, err)
+		} else {
+			log.Printf("[Qdrant Import OK] Чат %d: Проверкой (IMPORT_VERIFY) подтверждено %d точек в коллекции.", chatID, importedCount)
+		}
+	}
 
 	return importedCount, skippedCount, err // Возвращаем первую возникшую ошибку
 }
@@ -796,9 +1323,7 @@ func (qs *QdrantStorage) processMessageChunk(
 		}
 
 		// Генерируем UUID v5
-		uniqueIDStr := fmt.Sprintf("%d_%d", chatID, msg.ID)
-		pointUUID := uuid.NewSHA1(uuid.NameSpaceDNS, []byte(uniqueIDStr))
-		pointIDStrForUUID := pointUUID.String()
+		pointIDStrForUUID := qs.messagePointUUID(chatID, msg.ID)
 
 		// Проверка на дубликат в общем кеше
 		if existingPoints[pointIDStrForUUID] {
@@ -922,7 +1447,9 @@ func (qs *QdrantStorage) upsertPointsBatch(points []*qdrant.PointStruct) (int, i
 	}
 
 	// --- ИЗМЕНЕНИЕ: Устанавливаем wait = false для импорта ---
-	waitUpsert := false                                            // Не ждем подтверждения для ускорения импорта
+	// Не ждем подтверждения для ускорения импорта. В режиме IMPORT_VERIFY ждем,
+	// иначе последующая проверка может не увидеть еще не примененные точки.
+	waitUpsert := qs.importVerify
 	resp, err := qs.client.Upsert(upsertCtx, &qdrant.UpsertPoints{ // Используем upsertCtx
 		CollectionName: qs.collectionName,
 		Points:         points,
@@ -957,6 +1484,19 @@ func (qs *QdrantStorage) upsertPointsBatch(points []*qdrant.PointStruct) (int, i
 		return 0, len(points), fmt.Errorf("upsert вернул nil response без ошибки")
 	}
 
+	if qs.importVerify {
+		storedCount, verifyErr := qs.countStoredPoints(points)
+		if verifyErr != nil {
+			log.Printf("[Qdrant UpsertBatch WARN] Не удалось проверить сохранение батча (%d точек): %v", len(points), verifyErr)
+			return 0, len(points), fmt.Errorf("ошибка проверки Upsert батча: %w", verifyErr)
+		}
+		if storedCount != len(points) {
+			log.Printf("[Qdrant UpsertBatch WARN] После Upsert найдено %d из %d точек батча.", storedCount, len(points))
+		}
+		importedCount = storedCount
+		skippedCount = len(points) - storedCount
+	}
+
 	if qs.debug {
 		log.Printf("[Qdrant UpsertBatch DEBUG] Успешно выполнен Upsert для %d точек.", importedCount)
 	}
@@ -964,6 +1504,33 @@ func (qs *QdrantStorage) upsertPointsBatch(points []*qdrant.PointStruct) (int, i
 	return importedCount, skippedCount, nil
 }
 
+// countStoredPoints запрашивает точки батча по ID и возвращает, сколько из них есть в коллекции.
+// Используется в режиме IMPORT_VERIFY для подтверждения результата Upsert.
+func (qs *QdrantStorage) countStoredPoints(points []*qdrant.PointStruct) (int, error) {
+	ids := make([]*qdrant.PointId, 0, len(points))
+	for _, point := range points {
+		ids = append(ids, point.Id)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), qs.timeout)
+	defer cancel()
+	getCtx := ctx
+	if apiKey := qs.getApiKeyFromConfig(); apiKey != "" {
+		md := metadata.New(map[string]string{"api-key": apiKey})
+		getCtx = metadata.NewOutgoingContext(ctx, md)
+	}
+
+	resp, err := qs.client.Get(getCtx, &qdrant.GetPoints{
+		CollectionName: qs.collectionName,
+		Ids:            ids,
+		WithPayload:    qdrant.NewWithPayload(false),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("ошибка получения точек для проверки: %w", err)
+	}
+	return len(resp.GetResult()), nil
+}
+
 // messagePayloadToQdrantMap конвертирует *MessagePayload в map[string]*qdrant.Value для Qdrant Payload.
 func (qs *QdrantStorage) messagePayloadToQdrantMap(p *MessagePayload) map[string]*qdrant.Value {
 	if p == nil {
@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/Henry-Case-dev/rofloslav/internal/types"
+	"github.com/qdrant/go-client/qdrant"
+)
+
+func TestPayloadToMessage(t *testing.T) {
+	qs := &QdrantStorage{}
+	tests := []struct {
+		name    string
+		payload map[string]*qdrant.Value
+		want    types.Message
+		wantErr bool
+	}{
+		{
+			name: "все поля",
+			payload: map[string]*qdrant.Value{
+				"chat_id":    qdrant.NewValueInt(-100),
+				"message_id": qdrant.NewValueInt(42),
+				"date":       qdrant.NewValueInt(1700000000),
+				"text":       qdrant.NewValueString("привет"),
+				"user_id":    qdrant.NewValueInt(7),
+				"user_name":  qdrant.NewValueString("user"),
+				"first_name": qdrant.NewValueString("Имя"),
+				"is_bot":     qdrant.NewValueBool(true),
+			},
+			want: types.Message{ID: 42, ChatID: -100, UserID: 7, UserName: "user", FirstName: "Имя", IsBot: true, Text: "привет", Timestamp: 1700000000, Role: "user"},
+		},
+		{
+			name: "без необязательных полей",
+			payload: map[string]*qdrant.Value{
+				"message_id": qdrant.NewValueInt(42),
+				"date":       qdrant.NewValueInt(1700000000),
+				"text":       qdrant.NewValueString("привет"),
+			},
+			want: types.Message{ID: 42, Text: "привет", Timestamp: 1700000000, Role: "user"},
+		},
+		{
+			name: "без message_id",
+			payload: map[string]*qdrant.Value{
+				"chat_id": qdrant.NewValueInt(-100),
+				"date":    qdrant.NewValueInt(1700000000),
+				"text":    qdrant.NewValueString("привет"),
+			},
+			wantErr: true,
+		},
+		{
+			name: "неверный тип date",
+			payload: map[string]*qdrant.Value{
+				"message_id": qdrant.NewValueInt(42),
+				"date":       qdrant.NewValueString("вчера"),
+				"text":       qdrant.NewValueString("привет"),
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := qs.payloadToMessage(tt.payload)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("payloadToMessage() = %+v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("payloadToMessage() error = %v", err)
+			}
+			if got.ID != tt.want.ID || got.ChatID != tt.want.ChatID || got.UserID != tt.want.UserID ||
+				got.UserName != tt.want.UserName || got.FirstName != tt.want.FirstName || got.IsBot != tt.want.IsBot ||
+				got.Text != tt.want.Text || got.Timestamp != tt.want.Timestamp || got.Role != tt.want.Role {
+				t.Errorf("payloadToMessage() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
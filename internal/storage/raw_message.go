@@ -0,0 +1,39 @@
+package storage
+
+import (
+	"encoding/json"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// RawMessageJSON сериализует сообщение целиком для STORE_RAW_UPDATES (команда /raw).
+// Вызывается для исходного сообщения из обновления, до очистки текста в хранилищах.
+// Личные данные удаляются по тем же правилам, что и в MessageText: номер телефона контакта
+// не сохраняется никогда, имя - только с STORE_CONTACT_NAMES, координаты - только с STORE_LOCATION.
+func RawMessageJSON(msg *tgbotapi.Message) (json.RawMessage, error) {
+	return json.Marshal(redactRawMessage(msg))
+}
+
+// redactRawMessage возвращает копию сообщения без личных данных (исходное сообщение не изменяется).
+func redactRawMessage(msg *tgbotapi.Message) *tgbotapi.Message {
+	if msg == nil {
+		return nil
+	}
+	redacted := *msg
+	if msg.Contact != nil {
+		contact := *msg.Contact
+		contact.PhoneNumber = ""
+		contact.VCard = ""
+		if !messageTextPrivacy.storeContactNames {
+			contact.FirstName = ""
+			contact.LastName = ""
+		}
+		redacted.Contact = &contact
+	}
+	if !messageTextPrivacy.storeLocation {
+		redacted.Location = nil
+		redacted.Venue = nil
+	}
+	redacted.ReplyToMessage = redactRawMessage(msg.ReplyToMessage)
+	return &redacted
+}
@@ -0,0 +1,166 @@
+package storage
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/Henry-Case-dev/rofloslav/internal/types"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// isZeroWidth - невидимые символы нулевой ширины. Подряд идущие такие символы схлопываются в один:
+// одиночный ZWJ нужен составным эмодзи, а цепочки используются для обхода фильтров и подмены ников.
+func isZeroWidth(r rune) bool {
+	switch r {
+	case '\u200b', '\u200c', '\u200d', '\u2060', '\ufeff':
+		return true
+	}
+	return false
+}
+
+// isBidiControl - управляющие символы направления текста (RLO и т.п.), которыми подменяют
+// отображение текста. Удаляются.
+func isBidiControl(r rune) bool {
+	return r >= '\u202a' && r <= '\u202e' || r >= '\u2066' && r <= '\u2069'
+}
+
+// SanitizeText удаляет из текста управляющие символы (кроме перевода строки и табуляции),
+// символы направления текста и некорректный UTF-8, а цепочки символов нулевой ширины сводит к одному.
+func SanitizeText(text string) string {
+	sanitized, _ := sanitizeTextWithOffsets(text)
+	return sanitized
+}
+
+// sanitizeTextWithOffsets работает как SanitizeText и дополнительно возвращает соответствие смещений
+// в UTF-16 (в них Telegram задает сущности): offsets[старое смещение] = новое смещение.
+// Если текст не изменился, offsets равен nil.
+func sanitizeTextWithOffsets(text string) (string, []int) {
+	if !needsSanitizing(text) {
+		return text, nil
+	}
+	var sb strings.Builder
+	sb.Grow(len(text))
+	offsets := make([]int, 0, len(text)+1)
+	newPos := 0
+	prevZeroWidth := false
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		i += size
+		if r == utf8.RuneError && size == 1 {
+			continue // Некорректный байт: в смещениях Telegram его нет
+		}
+		width := utf16.RuneLen(r)
+		keep := !isBidiControl(r) && !(unicode.IsControl(r) && r != '\n' && r != '\t')
+		if isZeroWidth(r) {
+			keep = !prevZeroWidth
+			prevZeroWidth = true
+		} else {
+			prevZeroWidth = false
+		}
+		// Смещение внутри суррогатной пары относим к началу символа
+		for j := 0; j < width; j++ {
+			offsets = append(offsets, newPos)
+		}
+		if keep {
+			sb.WriteRune(r)
+			newPos += width
+		}
+	}
+	offsets = append(offsets, newPos)
+	return sb.String(), offsets
+}
+
+// needsSanitizing проверяет, есть ли в тексте что удалять, чтобы не копировать чистые сообщения.
+func needsSanitizing(text string) bool {
+	if !utf8.ValidString(text) {
+		return true
+	}
+	prevZeroWidth := false
+	for _, r := range text {
+		if isBidiControl(r) || unicode.IsControl(r) && r != '\n' && r != '\t' || isZeroWidth(r) && prevZeroWidth {
+			return true
+		}
+		prevZeroWidth = isZeroWidth(r)
+	}
+	return false
+}
+
+// remapEntity пересчитывает смещение и длину сущности после санитизации текста.
+// ok = false, если от сущности не осталось текста.
+func remapEntity(offset, length int, offsets []int) (newOffset, newLength int, ok bool) {
+	last := len(offsets) - 1
+	clamp := func(pos int) int {
+		if pos < 0 {
+			return 0
+		}
+		if pos > last {
+			return last
+		}
+		return pos
+	}
+	start := offsets[clamp(offset)]
+	end := offsets[clamp(offset+length)]
+	return start, end - start, end > start
+}
+
+// sanitizeEntities пересчитывает смещения сущностей после санитизации текста.
+// Сущности, от которых не осталось текста, отбрасываются.
+func sanitizeEntities(entities []tgbotapi.MessageEntity, offsets []int) []tgbotapi.MessageEntity {
+	if offsets == nil || len(entities) == 0 {
+		return entities
+	}
+	result := make([]tgbotapi.MessageEntity, 0, len(entities))
+	for _, entity := range entities {
+		var ok bool
+		if entity.Offset, entity.Length, ok = remapEntity(entity.Offset, entity.Length, offsets); ok {
+			result = append(result, entity)
+		}
+	}
+	return result
+}
+
+// sanitizeMessage возвращает сообщение с очищенными SanitizeText текстом и подписью
+// (включая сообщение, на которое оно отвечает), пересчитывая смещения сущностей.
+// Исходное сообщение не изменяется: его одновременно обрабатывает бот. Чистое сообщение возвращается как есть.
+func sanitizeMessage(msg *tgbotapi.Message) *tgbotapi.Message {
+	if msg == nil {
+		return nil
+	}
+	text, textOffsets := sanitizeTextWithOffsets(msg.Text)
+	caption, captionOffsets := sanitizeTextWithOffsets(msg.Caption)
+	reply := sanitizeMessage(msg.ReplyToMessage)
+	if textOffsets == nil && captionOffsets == nil && reply == msg.ReplyToMessage {
+		return msg
+	}
+
+	sanitized := *msg
+	sanitized.Text = text
+	sanitized.Entities = sanitizeEntities(msg.Entities, textOffsets)
+	sanitized.Caption = caption
+	sanitized.CaptionEntities = sanitizeEntities(msg.CaptionEntities, captionOffsets)
+	sanitized.ReplyToMessage = reply
+	return &sanitized
+}
+
+// sanitizeImportedMessage очищает текст сообщения из файла импорта так же, как sanitizeMessage
+// очищает живые сообщения, пересчитывая смещения сущностей.
+func sanitizeImportedMessage(msg types.Message) types.Message {
+	text, offsets := sanitizeTextWithOffsets(msg.Text)
+	if offsets == nil {
+		return msg
+	}
+	msg.Text = text
+	if len(msg.Entities) > 0 {
+		entities := make([]types.MessageEntity, 0, len(msg.Entities))
+		for _, entity := range msg.Entities {
+			var ok bool
+			if entity.Offset, entity.Length, ok = remapEntity(entity.Offset, entity.Length, offsets); ok {
+				entities = append(entities, entity)
+			}
+		}
+		msg.Entities = entities
+	}
+	return msg
+}
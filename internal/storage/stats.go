@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"sort"
+	"time"
+)
+
+// ChatStats - агрегированная статистика сообщений чата за период.
+type ChatStats struct {
+	Since         time.Time   // Начало периода
+	TotalMessages int         // Всего сообщений от пользователей (без ботов и служебных)
+	UniqueUsers   int         // Количество разных авторов
+	TopUsers      []UserStats // Авторы по убыванию числа сообщений
+	HourCounts    [24]int     // Сообщения по часам суток (в часовом поясе Since)
+}
+
+// UserStats - количество сообщений одного пользователя.
+type UserStats struct {
+	UserID int64
+	Name   string // @username или имя
+	Count  int
+}
+
+// BusiestHour возвращает самый активный час суток и число сообщений в нем (-1, если сообщений нет).
+func (s *ChatStats) BusiestHour() (int, int) {
+	hour, count := -1, 0
+	for h, c := range s.HourCounts {
+		if c > count {
+			hour, count = h, c
+		}
+	}
+	return hour, count
+}
+
+// chatStatsBuilder накапливает статистику по сообщениям, поступающим из любого хранилища.
+type chatStatsBuilder struct {
+	stats *ChatStats
+	users map[int64]*UserStats
+}
+
+func newChatStatsBuilder(since time.Time) *chatStatsBuilder {
+	return &chatStatsBuilder{
+		stats: &ChatStats{Since: since},
+		users: make(map[int64]*UserStats),
+	}
+}
+
+// add учитывает одно сообщение. Сообщения до начала периода и от ботов пропускаются.
+func (sb *chatStatsBuilder) add(userID int64, userName, firstName string, isBot bool, date int) {
+	if isBot || userID == 0 || int64(date) < sb.stats.Since.Unix() {
+		return
+	}
+	sb.stats.TotalMessages++
+	sb.stats.HourCounts[time.Unix(int64(date), 0).In(sb.stats.Since.Location()).Hour()]++
+
+	user, ok := sb.users[userID]
+	if !ok {
+		user = &UserStats{UserID: userID}
+		sb.users[userID] = user
+	}
+	user.Count++
+	if name := statsUserName(userName, firstName); name != "" {
+		user.Name = name
+	}
+}
+
+// result возвращает итоговую статистику с авторами, отсортированными по активности.
+func (sb *chatStatsBuilder) result() *ChatStats {
+	sb.stats.UniqueUsers = len(sb.users)
+	sb.stats.TopUsers = make([]UserStats, 0, len(sb.users))
+	for _, user := range sb.users {
+		sb.stats.TopUsers = append(sb.stats.TopUsers, *user)
+	}
+	sort.Slice(sb.stats.TopUsers, func(i, j int) bool {
+		if sb.stats.TopUsers[i].Count != sb.stats.TopUsers[j].Count {
+			return sb.stats.TopUsers[i].Count > sb.stats.TopUsers[j].Count
+		}
+		return sb.stats.TopUsers[i].UserID < sb.stats.TopUsers[j].UserID
+	})
+	return sb.stats
+}
+
+// statsUserName возвращает @username или имя, если username не задан.
+func statsUserName(userName, firstName string) string {
+	if userName != "" {
+		return "@" + userName
+	}
+	return firstName
+}
@@ -1,6 +1,8 @@
 package storage
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"time"
@@ -20,6 +22,14 @@ type HistoryStorage interface {
 	// AddMessage добавляет одно сообщение в историю чата (в память).
 	AddMessage(chatID int64, message *tgbotapi.Message)
 
+	// AddMessageWithRaw добавляет сообщение вместе с его полным JSON, сериализованным до очистки
+	// (см. RawMessageJSON). JSON сохраняется только с STORE_RAW_UPDATES.
+	AddMessageWithRaw(chatID int64, message *tgbotapi.Message, raw json.RawMessage)
+
+	// UpdateMessage обновляет ранее сохраненное сообщение (например, после редактирования).
+	// Сообщения, которых нет в хранилище, игнорируются или добавляются - на усмотрение реализации.
+	UpdateMessage(chatID int64, message *tgbotapi.Message)
+
 	// AddMessagesToContext добавляет несколько сообщений в историю чата (в память).
 	AddMessagesToContext(chatID int64, messages []*tgbotapi.Message)
 
@@ -39,6 +49,12 @@ type HistoryStorage interface {
 	// ClearChatHistory очищает историю для чата из памяти.
 	ClearChatHistory(chatID int64)
 
+	// ClearUserHistory удаляет все сообщения пользователя в чате (включая долговременную память).
+	ClearUserHistory(chatID int64, userID int64) error
+
+	// DeleteMessage удаляет одно сообщение из хранилища (включая вектор в долговременной памяти).
+	DeleteMessage(chatID int64, messageID int) error
+
 	// SaveAllChatHistories сохраняет историю всех чатов из памяти в персистентное хранилище.
 	SaveAllChatHistories() error
 
@@ -51,11 +67,46 @@ type HistoryStorage interface {
 	// Возвращает количество импортированных и пропущенных сообщений.
 	ImportMessagesFromJSONFile(chatID int64, filePath string) (importedCount int, skippedCount int, err error)
 
+	// GetRandomMessage возвращает случайное сохраненное сообщение чата (без команд и служебных сообщений).
+	// Возвращает nil, nil если подходящих сообщений нет.
+	GetRandomMessage(chatID int64) (*types.Message, error)
+
+	// GetRawMessage возвращает сохраненный полный JSON сообщения (STORE_RAW_UPDATES) для отладки.
+	// Возвращает nil, nil если сообщение не найдено или сохранено без JSON;
+	// ErrNotSupported - если STORE_RAW_UPDATES выключен.
+	GetRawMessage(chatID int64, messageID int) ([]byte, error)
+
+	// GetChatStats возвращает статистику сообщений чата начиная с since
+	// (количество сообщений, активные пользователи, распределение по часам).
+	GetChatStats(chatID int64, since time.Time) (*ChatStats, error)
+
 	// FindRelevantMessages ищет сообщения в истории чата, релевантные заданному тексту.
 	// Возвращает до `limit` наиболее релевантных сообщений.
 	FindRelevantMessages(chatID int64, queryText string, limit int) ([]types.Message, error)
+
+	// --- Возможности хранилища ---
+	// Бот проверяет их вместо приведения к конкретному типу хранилища.
+
+	// BackendName возвращает название реализации хранилища для логов и сообщений ("qdrant", "local").
+	BackendName() string
+
+	// SupportsVectorSearch сообщает, ищет ли FindRelevantMessages по эмбеддингам (долговременная память).
+	// Такие хранилища также отдают информацию о коллекции через GetCollectionInfo.
+	SupportsVectorSearch() bool
+
+	// GetCollectionInfo возвращает размер и состояние векторной коллекции.
+	// Хранилища без векторного поиска возвращают ErrNotSupported.
+	GetCollectionInfo() (*CollectionStats, error)
 }
 
+// ErrNotSupported возвращается методами, которые не поддерживаются реализацией хранилища.
+var ErrNotSupported = errors.New("операция не поддерживается этим хранилищем")
+
+// ErrVectorDimensionMismatch возвращается NewQdrantStorage, если размерность существующей коллекции
+// не совпадает с размерностью модели эмбеддингов (QDRANT_CHECK_DIMENSION). Откат на LocalStorage
+// при этой ошибке не выполняется: бот не должен молча работать без долговременной памяти.
+var ErrVectorDimensionMismatch = errors.New("размерность коллекции Qdrant не совпадает с размерностью эмбеддингов")
+
 // --- Конец Интерфейса ---
 
 // --- УДАЛЕНА СТАРАЯ СТРУКТУРА Storage и ЕЕ МЕТОДЫ ---
@@ -79,16 +130,21 @@ func (s *Storage) ClearChatHistory(...) { ... }
 // --- Структуры для конвертации ---
 
 type StoredMessage struct {
-	MessageID      int                      `json:"message_id"`
-	FromID         int64                    `json:"from_id"`
-	FromIsBot      bool                     `json:"from_is_bot"`
-	FromFirstName  string                   `json:"from_first_name"`
-	FromLastName   string                   `json:"from_last_name"`
-	FromUserName   string                   `json:"from_username"`
-	Date           int                      `json:"date"`
-	Text           string                   `json:"text"`
-	ReplyToMessage *StoredMessage           `json:"reply_to_message,omitempty"`
-	Entities       []tgbotapi.MessageEntity `json:"entities,omitempty"`
+	MessageID       int                      `json:"message_id"`
+	FromID          int64                    `json:"from_id"`
+	FromIsBot       bool                     `json:"from_is_bot"`
+	FromFirstName   string                   `json:"from_first_name"`
+	FromLastName    string                   `json:"from_last_name"`
+	FromUserName    string                   `json:"from_username"`
+	Date            int                      `json:"date"`
+	Text            string                   `json:"text"`
+	Caption         string                   `json:"caption,omitempty"` // Подпись медиа (если Text пуст)
+	ReplyToMessage  *StoredMessage           `json:"reply_to_message,omitempty"`
+	Entities        []tgbotapi.MessageEntity `json:"entities,omitempty"`
+	CaptionEntities []tgbotapi.MessageEntity `json:"caption_entities,omitempty"`
+	ForwardDate     int                      `json:"forward_date,omitempty"`   // Дата оригинала для пересланных сообщений
+	ForwardedFrom   string                   `json:"forwarded_from,omitempty"` // Автор оригинала (см. ForwardSource)
+	Raw             json.RawMessage          `json:"raw,omitempty"`            // Полное сообщение (STORE_RAW_UPDATES)
 }
 
 func ConvertToStoredMessage(msg *tgbotapi.Message) *StoredMessage {
@@ -96,10 +152,20 @@ func ConvertToStoredMessage(msg *tgbotapi.Message) *StoredMessage {
 		return nil
 	}
 	stored := &StoredMessage{
-		MessageID: msg.MessageID,
-		Date:      msg.Date,
-		Text:      msg.Text,
-		Entities:  msg.Entities,
+		MessageID:       msg.MessageID,
+		Date:            msg.Date,
+		Text:            msg.Text,
+		Caption:         msg.Caption,
+		Entities:        msg.Entities,
+		CaptionEntities: msg.CaptionEntities,
+	}
+	if stored.Text == "" && stored.Caption == "" {
+		// Опросы и другие сообщения без текста сохраняются текстовым описанием
+		stored.Text = MessageText(msg)
+	}
+	if forwardedFrom := ForwardSource(msg); forwardedFrom != "" {
+		stored.ForwardDate = msg.ForwardDate
+		stored.ForwardedFrom = forwardedFrom
 	}
 	if msg.From != nil {
 		stored.FromID = msg.From.ID
@@ -127,9 +193,16 @@ func ConvertToAPIMessage(stored *StoredMessage) *tgbotapi.Message {
 			LastName:  stored.FromLastName,
 			UserName:  stored.FromUserName,
 		},
-		Date:     stored.Date,
-		Text:     stored.Text,
-		Entities: stored.Entities,
+		Date:            stored.Date,
+		Text:            stored.Text,
+		Caption:         stored.Caption,
+		Entities:        stored.Entities,
+		CaptionEntities: stored.CaptionEntities,
+	}
+	if stored.ForwardedFrom != "" {
+		// Восстанавливаем только имя автора: полные данные пользователя/канала не сохраняются
+		msg.ForwardDate = stored.ForwardDate
+		msg.ForwardSenderName = stored.ForwardedFrom
 	}
 	if stored.ReplyToMessage != nil {
 		msg.ReplyToMessage = ConvertToAPIMessage(stored.ReplyToMessage)
@@ -144,15 +217,23 @@ func ConvertToAPIMessage(stored *StoredMessage) *tgbotapi.Message {
 // NewHistoryStorage создает и возвращает подходящую реализацию HistoryStorage
 // на основе конфигурации.
 func NewHistoryStorage(cfg *config.Config, geminiClient *gemini.Client) (HistoryStorage, error) {
+	// Без клиента Gemini (ALLOW_LLMLESS_START) эмбеддинги недоступны, Qdrant использовать нельзя
+	if geminiClient == nil {
+		log.Println("[Storage Factory WARN] Клиент Gemini недоступен, используется LocalStorage без долговременной памяти.")
+		return NewLocalStorage(cfg)
+	}
 	// Пока что принудительно используем QdrantStorage
 	// В будущем можно добавить флаг в конфиг для выбора
 	log.Println("[Storage Factory] Попытка инициализации Qdrant хранилища.")
 	qdrantStorage, err := NewQdrantStorage(cfg, geminiClient)
 	if err != nil {
 		log.Printf("[Storage Factory ERROR] Ошибка инициализации QdrantStorage: %v", err)
+		if errors.Is(err, ErrVectorDimensionMismatch) {
+			return nil, err
+		}
 		// Можно добавить откат на LocalStorage, если Qdrant недоступен
 		log.Printf("[Storage Factory WARN] Ошибка Qdrant, откат на LocalStorage (ДЛЯ ОТЛАДКИ).")
-		localStorage, localErr := NewLocalStorage(cfg.ContextWindow)
+		localStorage, localErr := NewLocalStorage(cfg)
 		if localErr != nil {
 			log.Printf("[Storage Factory ERROR] Ошибка инициализации ЗАПАСНОГО LocalStorage: %v", localErr)
 			return nil, fmt.Errorf("ошибка инициализации Qdrant (%v) и запасного LocalStorage (%w)", err, localErr)
@@ -3,17 +3,18 @@ package types
 // Message представляет собой унифицированную структуру сообщения,
 // используемую для хранения в Qdrant и передачи в Gemini.
 type Message struct {
-	ID           int64           `json:"id"`                // Уникальный ID сообщения (обычно из Telegram)
-	ChatID       int64           `json:"chat_id"`           // ID чата, к которому относится сообщение
-	UserID       int64           `json:"user_id,omitempty"` // ID пользователя-отправителя
-	UserName     string          `json:"user_name,omitempty"`
-	FirstName    string          `json:"first_name,omitempty"`
-	IsBot        bool            `json:"is_bot,omitempty"`
-	Text         string          `json:"text"`      // Текст сообщения
-	Timestamp    int             `json:"timestamp"` // Unix timestamp времени отправки
-	ReplyToMsgID int             `json:"reply_to_msg_id,omitempty"`
-	Role         string          `json:"role,omitempty"`     // Роль отправителя ("user", "model")
-	Entities     []MessageEntity `json:"entities,omitempty"` // Сущности в тексте (ссылки, упоминания и т.д.)
+	ID            int64           `json:"id"`                // Уникальный ID сообщения (обычно из Telegram)
+	ChatID        int64           `json:"chat_id"`           // ID чата, к которому относится сообщение
+	UserID        int64           `json:"user_id,omitempty"` // ID пользователя-отправителя
+	UserName      string          `json:"user_name,omitempty"`
+	FirstName     string          `json:"first_name,omitempty"`
+	IsBot         bool            `json:"is_bot,omitempty"`
+	Text          string          `json:"text"`      // Текст сообщения
+	Timestamp     int             `json:"timestamp"` // Unix timestamp времени отправки
+	ReplyToMsgID  int             `json:"reply_to_msg_id,omitempty"`
+	Role          string          `json:"role,omitempty"`           // Роль отправителя ("user", "model")
+	Entities      []MessageEntity `json:"entities,omitempty"`       // Сущности в тексте (ссылки, упоминания и т.д.)
+	ForwardedFrom string          `json:"forwarded_from,omitempty"` // Автор оригинала для пересланных сообщений (пусто - не пересланное)
 
 	// Поле Embedding используется только при чтении из Qdrant/передаче в Gemini,
 	// в JSON его обычно нет.
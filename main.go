@@ -2,9 +2,13 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/Henry-Case-dev/rofloslav/internal/bot"
@@ -31,30 +35,52 @@ func main() {
 	}
 	log.Println("--- Configuration Loaded ---")
 
+	// Режим проверки конфигурации: проверяем подключения и завершаемся, не запуская бота
+	if cfg.ConfigCheck {
+		os.Exit(runConfigCheck(context.Background(), cfg))
+	}
+
 	// --- Инициализация клиентов и хранилищ ---
 	ctx := context.Background()
 
 	// Инициализация клиента Gemini
-	geminiClient, err := gemini.NewClient(ctx, cfg.GeminiAPIKey, cfg.GeminiModelName, cfg.GeminiEmbeddingModelName)
+	// При ALLOW_LLMLESS_START бот может работать без Gemini: только сохраняет сообщения и выполняет команды
+	var geminiClient *gemini.Client
+	if cfg.GeminiAPIKey != "" {
+		geminiClient, err = gemini.NewClient(ctx, cfg.GeminiAPIKey, cfg.GeminiModelName, cfg.GeminiEmbeddingModelName, time.Duration(cfg.ResponseTimeoutSec)*time.Second, cfg.GeminiSafetyBlockRetry)
+	} else {
+		err = fmt.Errorf("GEMINI_API_KEY не задан")
+	}
 	if err != nil {
-		log.Printf("!!! FATAL: Ошибка инициализации клиента Gemini: %v", err)
-		time.Sleep(15 * time.Second)
-		panic(fmt.Sprintf("Gemini client initialization error: %v", err))
+		if !cfg.AllowLLMLessStart {
+			log.Printf("!!! FATAL: Ошибка инициализации клиента Gemini: %v", err)
+			time.Sleep(15 * time.Second)
+			panic(fmt.Sprintf("Gemini client initialization error: %v", err))
+		}
+		log.Printf("!!! WARNING: Ошибка инициализации клиента Gemini: %v", err)
+		log.Println("!!! WARNING: Бот запускается БЕЗ LLM (ALLOW_LLMLESS_START=true): сообщения сохраняются, ответы отключены.")
+		geminiClient = nil
+	} else {
+		defer geminiClient.Close() // Закрываем клиент при завершении main
+		log.Println("--- Gemini Client Initialized ---")
 	}
-	defer geminiClient.Close() // Закрываем клиент при завершении main
-	log.Println("--- Gemini Client Initialized ---")
+
+	storage.ConfigureMessageText(cfg)
 
 	// Инициализация основного хранилища
 	primaryStorage, err := storage.NewHistoryStorage(cfg, geminiClient)
 	if err != nil {
+		if errors.Is(err, storage.ErrVectorDimensionMismatch) {
+			log.Printf("!!! FATAL: Коллекция Qdrant несовместима с моделью эмбеддингов, запуск без долговременной памяти невозможен.")
+		}
 		log.Printf("!!! FATAL: Ошибка инициализации основного хранилища: %v", err)
 		time.Sleep(15 * time.Second)
 		panic(fmt.Sprintf("Primary storage initialization error: %v", err))
 	}
-	log.Println("--- Primary Storage Initialized ---")
+	log.Printf("--- Primary Storage Initialized (%s) ---", primaryStorage.BackendName())
 
 	// Инициализация локального хранилища для саммари
-	localHistoryStorage, err := storage.NewLocalStorage(cfg.ContextWindow)
+	localHistoryStorage, err := storage.NewLocalStorage(cfg)
 	if err != nil {
 		// Ошибка локального хранилища не фатальна, но логируем
 		log.Printf("!!! WARNING: Ошибка инициализации локального хранилища: %v", err)
@@ -93,17 +119,15 @@ func main() {
 	log.Printf("--- HTTP Server Goroutine Launched on %s ---", serverAddr)
 	// --- Конец HTTP сервера ---
 
-	log.Printf("--- Application Ready. Waiting indefinitely. ---")
+	log.Printf("--- Application Ready. Waiting for shutdown signal. ---")
 
-	// Ожидаем бесконечно, игнорируем сигналы завершения.
-	// Это нужно для Amvera, чтобы контейнер оставался RUNNING.
-	select {}
+	// Работаем до SIGINT/SIGTERM (остановка или передеплой контейнера в Amvera),
+	// затем останавливаем бота: он сохраняет отложенные настройки чатов и состояние лимитов.
+	signalCtx, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+	<-signalCtx.Done()
 
-	// Этот код больше никогда не будет выполнен в Amvera.
-	// Оставляем его закомментированным на случай локальных тестов.
-	/*
-		log.Println("Остановка бота (из main)...") // Добавляем лог для ясности
-		bot.Stop()
-		log.Println("Приложение остановлено")
-	*/
+	log.Println("Получен сигнал завершения, остановка бота...")
+	botInstance.Stop()
+	log.Println("Приложение остановлено")
 }